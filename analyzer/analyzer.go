@@ -1,25 +1,27 @@
 package analyzer
 
 import (
+	"fmt"
+	"go/token"
 	"go/types"
+	"sync"
 
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
 	"golang.org/x/tools/go/analysis/passes/inspect"
-	"golang.org/x/tools/go/packages"
 )
 
 var (
-	// Analyzer defines the analyzer for closecheck
-	Analyzer = &analysis.Analyzer{
-		Name:      "closecheck",
-		Doc:       "check that any io.Closer in return a value is closed",
-		Run:       run,
-		Requires:  []*analysis.Analyzer{inspect.Analyzer},
-		FactTypes: []analysis.Fact{new(ioCloserFunc)},
-	}
+	// Analyzer defines the analyzer for closecheck with the default options
+	Analyzer = New(Options{})
+
+	closerType = newCloserInterface()
+)
 
-	closerType          *types.Interface
-	printStatementsMode bool
+const (
+	astEngine = "ast"
+	ssaEngine = "ssa"
 )
 
 type isCloser struct {
@@ -27,35 +29,182 @@ type isCloser struct {
 
 func (c *isCloser) AFact() {}
 
-func run(pass *analysis.Pass) (interface{}, error) {
-	fVisitor := &FunctionVisitor{pass: pass}
+// Options are the settings of an analyzer created with New, every analyzer has its own copy of them
+type Options struct {
+	// Name is the name of the analyzer, "closecheck" by default. Instances run together need different names.
+	Name string
+	// Engine is the engine used to track the closers: "ast", the default, or "ssa" (experimental). It decides the
+	// analyzers that are required, so it can't be changed once the analyzer is created.
+	Engine string
+	// Config has the extra resources, release functions and exclusions, the -config flag replaces it
+	Config *Config
+	// Debug selects the traces printed while the packages are analyzed
+	Debug DebugOptions
+}
+
+// DebugOptions select the traces printed while the packages are analyzed
+type DebugOptions struct {
+	// Statements prints the statements visited while checking the assignments, like the -print-statements flag
+	Statements bool
+	// Functions prints the statements visited while looking for the functions that close their arguments
+	Functions bool
+	// CloserFunctions prints the functions that receive a closer
+	CloserFunctions bool
+	// Failures prints the functions that don't close the closers they assign
+	Failures bool
+}
+
+// checker runs the passes of an analyzer, its options are only modified by the flags before the passes run
+type checker struct {
+	options    Options
+	configPath string
+
+	mu         sync.Mutex
+	loaded     *Config
+	loadedFrom string
+	loadErr    error
+}
+
+// settings are the options of a checker resolved for a pass
+type settings struct {
+	*Config
+	rules ruleSet
+	debug DebugOptions
+}
+
+// New creates an analyzer with the given options
+func New(options Options) *analysis.Analyzer {
+	if options.Name == "" {
+		options.Name = "closecheck"
+	}
+
+	if options.Engine == "" {
+		options.Engine = astEngine
+	}
+
+	c := &checker{options: options}
+
+	analyzer := &analysis.Analyzer{
+		Name:      options.Name,
+		Doc:       "check that any io.Closer in return a value is closed",
+		Run:       c.run,
+		Requires:  requires(options.Engine),
+		FactTypes: []analysis.Fact{new(ioCloserFunc), new(releasedFields), new(resultOwnership)},
+	}
+
+	analyzer.Flags.StringVar(&c.configPath, "config", "", "YAML or JSON file with extra resources, release functions and exclusions")
+	analyzer.Flags.BoolVar(&c.options.Debug.Statements, "print-statements", options.Debug.Statements, "print program trace")
+
+	return analyzer
+}
+
+// requires returns the analyzers needed by the engine, the SSA form is only built when the ssa engine is selected.
+// The driver reads them before the flags are parsed, so the engine can only be chosen when the analyzer is created.
+func requires(engine string) []*analysis.Analyzer {
+	if engine == ssaEngine {
+		return []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer, buildssa.Analyzer}
+	}
+
+	return []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer}
+}
+
+// newCloserInterface builds the io.Closer interface, it's built instead of loaded from the io package because
+// interfaces are satisfied by their method sets regardless of where they are declared
+func newCloserInterface() *types.Interface {
+	errorType := types.Universe.Lookup("error").Type()
+	results := types.NewTuple(types.NewVar(token.NoPos, nil, "", errorType))
+	closeMethod := types.NewFunc(token.NoPos, nil, "Close", types.NewSignature(nil, nil, results, false))
+
+	return types.NewInterfaceType([]*types.Func{closeMethod}, nil).Complete()
+}
+
+func (c *checker) run(pass *analysis.Pass) (interface{}, error) {
+	settings, err := c.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	fVisitor := &FunctionVisitor{pass: pass, settings: settings}
 	funcs := fVisitor.findFunctionsThatReceiveAnIOCloser()
+	fVisitor.findTypesThatReleaseFields()
+	fVisitor.findFunctionsThatReturnBorrowedClosers()
+
+	if settings.isPackageExcluded(pass.Pkg.Path()) {
+		return nil, nil
+	}
+
+	directives := findDirectives(excludePaths(pass, settings.Config))
+	pass = directives.filter()
+
+	fVisitor.reportDiagnostics(pass)
+
+	switch c.options.Engine {
+	case astEngine:
+		aVisitor := &AssignVisitor{pass: pass, settings: settings, closerFuncs: funcs, localGlobalVars: fVisitor.localGlobalVars}
+		aVisitor.checkFunctionsThatAssignCloser()
+	case ssaEngine:
+		sVisitor := &SSAVisitor{pass: pass, settings: settings}
+		sVisitor.checkCallsThatReturnCloser()
+	default:
+		return nil, fmt.Errorf("unknown engine %q, it must be %q or %q", c.options.Engine, astEngine, ssaEngine)
+	}
 
-	aVisitor := &AssignVisitor{pass: pass, closerFuncs: funcs, localGlobalVars: fVisitor.localGlobalVars}
-	aVisitor.checkFunctionsThatAssignCloser()
+	directives.reportProblems()
 
 	return nil, nil
 }
 
-func init() {
-	Analyzer.Flags.BoolVar(&printStatementsMode, "print-statements", false, "print program trace")
+// settings resolves the options of the checker for a pass, the config file is loaded again only when the -config
+// flag changes
+func (c *checker) settings() (*settings, error) {
+	config, err := c.config()
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings{
+		Config: config,
+		rules:  append(config.rules(), defaultResourceRules()...),
+		debug:  c.options.Debug,
+	}, nil
 }
 
-// init finds the io.Closer interface
-func init() {
-	cfg := &packages.Config{Mode: packages.NeedDeps | packages.NeedTypes, Tests: false}
+func (c *checker) config() (*Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	pkgs, err := packages.Load(cfg, "io")
-	if err != nil {
-		panic(err)
+	if c.loaded != nil && c.loadedFrom == c.configPath {
+		return c.loaded, c.loadErr
 	}
 
-	if len(pkgs) != 1 {
-		panic("couldn't load io package")
+	c.loadedFrom = c.configPath
+	c.loaded, c.loadErr = c.options.Config, nil
+
+	if c.configPath != "" {
+		c.loaded, c.loadErr = LoadConfig(c.configPath)
+	} else if c.loaded != nil {
+		c.loadErr = c.loaded.validate()
 	}
 
-	closerType = pkgs[0].Types.Scope().Lookup("Closer").Type().Underlying().(*types.Interface)
-	if closerType == nil {
-		panic("io.Closer not found")
+	if c.loaded == nil {
+		c.loaded = &Config{}
 	}
+
+	return c.loaded, c.loadErr
+}
+
+// excludePaths returns a copy of the pass that drops the diagnostics of the excluded files
+func excludePaths(pass *analysis.Pass, config *Config) *analysis.Pass {
+	if len(config.Exclude.Paths) == 0 {
+		return pass
+	}
+
+	filtered := *pass
+	filtered.Report = func(diag analysis.Diagnostic) {
+		if !config.isPathExcluded(pass.Fset.Position(diag.Pos).Filename) {
+			pass.Report(diag)
+		}
+	}
+
+	return &filtered
 }
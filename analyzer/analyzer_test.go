@@ -4,12 +4,107 @@ import (
 	"path/filepath"
 	"testing"
 
+	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
 )
 
 func Test(t *testing.T) {
 	path, _ := filepath.Abs("../samples")
 
 	//analysistest.Run(t, path, Analyzer, "http-response-external-closer")
-	analysistest.Run(t, path, Analyzer, "http-response-ignored", "http-response-not-assigned", "multi-assign", "http-response-on-go-statement", "http-response-on-defer-statement", "http-response-nopcloser", "global-var") // FIXME: "http-response-assigned",
+	analysistest.Run(t, path, Analyzer, "http-response-assigned", "http-response-ignored", "http-response-not-assigned", "multi-assign", "http-response-on-go-statement", "http-response-on-defer-statement", "http-response-nopcloser", "global-var", "cfg-paths", "statements", "loops", "resources", "directives", "ownership", "struct-field", "params", "helper-chain", "http-response-return", "borrowed", "use-after-close", "owned-fields", "closures", "collections", "variadic", "wrappers")
+}
+
+func TestSSAEngine(t *testing.T) {
+	path, _ := filepath.Abs("../samples")
+
+	analysistest.Run(t, path, New(Options{Engine: ssaEngine}), "http-response-ignored", "http-response-not-assigned", "multi-assign", "http-response-on-go-statement", "http-response-on-defer-statement", "http-response-nopcloser", "global-var", "ssa-aliases", "directives", "borrowed")
+}
+
+func TestEngineRequires(t *testing.T) {
+	requiresSSA := func(analyzer *analysis.Analyzer) bool {
+		for _, required := range analyzer.Requires {
+			if required == buildssa.Analyzer {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if requiresSSA(New(Options{})) {
+		t.Error("the ast engine requires the SSA form")
+	}
+
+	if !requiresSSA(New(Options{Engine: ssaEngine})) {
+		t.Error("the ssa engine doesn't require the SSA form")
+	}
+
+	if analyzer := New(Options{}); analyzer.Flags.Lookup("engine") != nil {
+		t.Error("the engine can be changed with a flag after the requirements are built")
+	}
+}
+
+func TestSuggestedFixes(t *testing.T) {
+	path, _ := filepath.Abs("../samples")
+
+	analysistest.RunWithSuggestedFixes(t, path, Analyzer, "defer-before-check", "missing-close")
+}
+
+func TestConfig(t *testing.T) {
+	path, _ := filepath.Abs("../samples")
+
+	for _, config := range []string{"closecheck.yaml", "closecheck.json"} {
+		t.Run(config, func(t *testing.T) {
+			analyzer := New(Options{})
+			if err := analyzer.Flags.Set("config", filepath.Join(path, "config", config)); err != nil {
+				t.Fatal(err)
+			}
+
+			analysistest.Run(t, path, analyzer, "config-resources", "config-excluded")
+		})
+	}
+}
+
+func TestConfigOption(t *testing.T) {
+	path, _ := filepath.Abs("../samples")
+
+	config, err := LoadConfig(filepath.Join(path, "config", "closecheck.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	analysistest.Run(t, path, New(Options{Config: config}), "config-resources", "config-excluded")
+}
+
+func TestDoubleClose(t *testing.T) {
+	path, _ := filepath.Abs("../samples")
+
+	config := &Config{Checks: Checks{DoubleClose: DoubleClose{Enabled: true, AllowCheckedClose: true}}}
+	analysistest.Run(t, path, New(Options{Config: config}), "double-close")
+
+	config = &Config{Checks: Checks{DoubleClose: DoubleClose{Enabled: true}}}
+	analysistest.Run(t, path, New(Options{Config: config}), "double-close-strict")
+}
+
+func TestIgnoredCloseErrors(t *testing.T) {
+	path, _ := filepath.Abs("../samples")
+
+	config := &Config{Checks: Checks{IgnoredCloseErrors: IgnoredCloseErrors{Enabled: true}}}
+	analysistest.RunWithSuggestedFixes(t, path, New(Options{Config: config}), "ignored-close-errors")
+}
+
+func TestCollectionsRequireLoop(t *testing.T) {
+	path, _ := filepath.Abs("../samples")
+
+	config := &Config{Collections: requireLoopCollections}
+	analysistest.Run(t, path, New(Options{Config: config}), "collections-require-loop")
+}
+
+func TestRegistrationFunctions(t *testing.T) {
+	path, _ := filepath.Abs("../samples")
+
+	config := &Config{RegistrationFunctions: []string{"(registrations.Registry).Add"}}
+	analysistest.Run(t, path, New(Options{Config: config}), "registrations")
 }
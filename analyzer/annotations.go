@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+const (
+	takesOwnershipAnnotation  = "//closecheck:takes-ownership"
+	returnsOwnedAnnotation    = "//closecheck:returns-owned"
+	returnsBorrowedAnnotation = "//closecheck:returns-borrowed"
+)
+
+// annotations are the ownership annotations in the doc comment of a function, like
+// `//closecheck:takes-ownership conn` for a function that releases or keeps the conn parameter, or
+// `//closecheck:returns-owned` for a constructor whose result owns the closers stored in it, or
+// `//closecheck:returns-borrowed` for a getter whose result is released by someone else
+type annotations struct {
+	takesOwnership    []string
+	takesOwnershipPos token.Pos
+	returnsOwned      bool
+	returnsBorrowed   bool
+}
+
+func parseAnnotations(doc *ast.CommentGroup) annotations {
+	ann := annotations{}
+	if doc == nil {
+		return ann
+	}
+
+	for _, c := range doc.List {
+		if rest := strings.TrimPrefix(c.Text, takesOwnershipAnnotation); rest != c.Text {
+			// the names can be followed by a comment
+			if i := strings.Index(rest, "//"); i >= 0 {
+				rest = rest[:i]
+			}
+
+			ann.takesOwnership = append(ann.takesOwnership, strings.FieldsFunc(rest, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			})...)
+			ann.takesOwnershipPos = c.Slash
+		} else if strings.TrimSpace(c.Text) == returnsOwnedAnnotation {
+			ann.returnsOwned = true
+		} else if strings.TrimSpace(c.Text) == returnsBorrowedAnnotation {
+			ann.returnsBorrowed = true
+		}
+	}
+
+	return ann
+}
+
+func (ann annotations) isEmpty() bool {
+	return len(ann.takesOwnership) == 0 && !ann.returnsOwned
+}
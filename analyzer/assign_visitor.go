@@ -7,26 +7,37 @@ import (
 	"go/types"
 	"log"
 	"strings"
-	"unicode"
 
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/cfg"
 	"golang.org/x/tools/go/types/typeutil"
 )
 
-var (
-	enableAssignDebugger = false
-	printFunctionFailure = false
-)
-
-// AssignVisitor is in charge of preprocessing packages to find functions that close io.Closers
+// AssignVisitor is in charge of checking that the closers assigned in a function are closed on every path
 type AssignVisitor struct {
 	pass            *analysis.Pass
+	settings        *settings
 	closerFuncs     map[*types.Func]*ioCloserFunc
 	localGlobalVars map[token.Pos]bool
+	cfgs            *ctrlflow.CFGs
+	checkedFuncLits map[*ast.FuncLit]bool
+	reportedFields  map[token.Pos]bool
+	reportedCloses  map[token.Pos]bool
+	ownedFields     map[*types.Var]*ownedField
+	// releasingClosures are the function literals being checked by closureReleases
+	releasingClosures map[*ast.FuncLit]bool
+	// closures are the function literals assigned to the local variables when they are declared
+	closures map[types.Object]*ast.FuncLit
+	// invokedVars are the local variables that are called or passed somewhere else
+	invokedVars map[types.Object]bool
 }
 
 func (av *AssignVisitor) debug(n ast.Node, text string, args ...interface{}) {
-	if !enableAssignDebugger {
+	if !av.settings.debug.Statements {
 		return
 	}
 
@@ -48,143 +59,136 @@ func (av *AssignVisitor) debug(n ast.Node, text string, args ...interface{}) {
 	_ = ast.Print(av.pass.Fset, n)
 }
 
+// posToClose is a closer assigned in a function, it's tracked from the node that assigned it until every exit of
+// the function
 type posToClose struct {
-	name                string
-	typeName            string
-	pos                 token.Pos
-	parent              *ast.Ident
-	wasClosedOrReturned bool
-}
-
-type field struct {
 	name     string
 	typeName string
-	pos      token.Pos
+	parent   *ast.Ident
+	obj      types.Object // variable holding the closer, nil for the blank identifier
+	field    *types.Var   // field of obj that has to be closed, nil if obj is the closer itself
+	errObj   types.Object // error returned along with the closer, if any
+	rule     *resourceRule
+	block    *cfg.Block
+	index    int
+	// storeReleases is set in the functions annotated with returns-owned, storing the closer in another value
+	// transfers it to the value returned to the caller
+	storeReleases bool
 }
 
-type returnVar struct {
-	needsClosing bool
-	typeName     string
-	fields       []field
-}
+// this function checks functions that assign a closer
+func (av *AssignVisitor) checkFunctionsThatAssignCloser() {
+	av.cfgs = av.pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+	av.checkedFuncLits = map[*ast.FuncLit]bool{}
+	av.reportedFields = map[token.Pos]bool{}
+	av.reportedCloses = map[token.Pos]bool{}
+	av.ownedFields = map[*types.Var]*ownedField{}
+	av.releasingClosures = map[*ast.FuncLit]bool{}
+
+	inspect := av.pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	av.indexClosures(inspect)
+
+	nodeFilter := []ast.Node{
+		(*ast.FuncDecl)(nil),
+		(*ast.FuncLit)(nil),
+	}
+
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			if fn.Body == nil {
+				return
+			}
 
-func (av *AssignVisitor) newReturnVar(t types.Type) returnVar {
-	if types.Implements(t, closerType) {
-		return returnVar{
-			needsClosing: true,
-			typeName:     t.String(),
-			fields:       []field{},
-		}
-	}
+			returnsOwned := parseAnnotations(fn.Doc).returnsOwned
 
-	// special case: a struct containing a io.Closer fields that implements io.Closer, like http.Response.Body
-	ptr, ok := t.Underlying().(*types.Pointer)
-	if !ok {
-		return returnVar{
-			needsClosing: false,
-			fields:       []field{},
-		}
-	}
+			if !av.traverse(av.cfgs.FuncDecl(fn), returnsOwned) && av.settings.debug.Failures {
+				fmt.Println("Printing function that failed")
 
-	str, ok := ptr.Elem().Underlying().(*types.Struct)
-	if !ok {
-		return returnVar{
-			needsClosing: false,
-			fields:       []field{},
+				_ = ast.Print(av.pass.Fset, fn)
+			}
+		case *ast.FuncLit:
+			av.checkFuncLit(fn)
 		}
-	}
-
-	fields := []field{}
+	})
 
-	for i := 0; i < str.NumFields(); i++ {
-		v := str.Field(i)
-		fieldName := v.Name()
+	av.checkOwnedFields()
+}
 
-		// TODO: don't ignore unexported fields if the struct is in the current package
-		if types.Implements(v.Type(), closerType) && unicode.IsUpper([]rune(fieldName)[0]) {
-			fields = append(fields, field{
-				name:     fieldName,
-				typeName: v.Type().String(),
-				pos:      v.Pos(),
-			})
-		}
+// checkFuncLit checks the body of a function literal only once, no matter how many times it's referenced
+func (av *AssignVisitor) checkFuncLit(lit *ast.FuncLit) bool {
+	if ok, wasChecked := av.checkedFuncLits[lit]; wasChecked {
+		return ok
 	}
 
-	return returnVar{
-		needsClosing: len(fields) > 0,
-		typeName:     t.String(),
-		fields:       fields,
-	}
+	ok := av.traverse(av.cfgs.FuncLit(lit), false)
+	av.checkedFuncLits[lit] = ok
+
+	return ok
 }
 
-// this function checks functions that assign a closer
-func (av *AssignVisitor) checkFunctionsThatAssignCloser() {
-	for _, file := range av.pass.Files {
-		for _, decl := range file.Decls {
-			fdecl, ok := decl.(*ast.FuncDecl)
-			if !ok || fdecl.Body == nil {
-				continue
-			}
+func (av *AssignVisitor) traverse(g *cfg.CFG, returnsOwned bool) bool {
+	ok := true
 
-			if !av.traverse(fdecl.Body.List) && printFunctionFailure {
-				fmt.Println("Printing function that failed")
+	for _, block := range g.Blocks {
+		if !block.Live {
+			continue
+		}
 
-				_ = ast.Print(av.pass.Fset, fdecl)
+		for i, node := range block.Nodes {
+			if !av.checkUnassignedCloser(node) {
+				ok = false
 			}
-		}
-	}
-}
 
-func (av *AssignVisitor) traverse(stmts []ast.Stmt) bool {
-	posListToClose := []*posToClose{}
-
-	for _, stmt := range stmts {
-		if len(posListToClose) == 0 {
-			switch castedStmt := stmt.(type) {
-			case *ast.ExprStmt:
-				call, ok := castedStmt.X.(*ast.CallExpr)
-				if ok && av.callReturnsCloser(call) {
-					av.pass.Reportf(call.Pos(), "return value won't be closed because it wasn't assigned") // FIXME: improve message
-					return false
+			av.recordOwnedFields(node)
+
+			for _, idToClose := range av.closersAssignedIn(node) {
+				idToClose.block = block
+				idToClose.index = i
+				idToClose.storeReleases = returnsOwned
+
+				if !av.checkPaths(idToClose) {
+					ok = false
 				}
-			case *ast.DeferStmt:
-				if av.callReturnsCloser(castedStmt.Call) {
-					av.pass.Reportf(castedStmt.Call.Pos(), "return value won't be closed because it's on defer statement") // FIXME: improve message
-					return false
+
+				if !av.checkUseAfterClose(g, idToClose) {
+					ok = false
 				}
-			case *ast.GoStmt:
-				if av.callReturnsCloser(castedStmt.Call) {
-					av.pass.Reportf(castedStmt.Call.Pos(), "return value won't be closed because it's on go statement") // FIXME: improve message
-					return false
+
+				if !av.checkUncalledClosures(g, idToClose) {
+					ok = false
 				}
-			}
-		}
 
-		for _, idToClose := range posListToClose {
-			if av.returnsOrClosesID(*idToClose, stmt) {
-				idToClose.wasClosedOrReturned = true
+				if av.settings.Checks.DoubleClose.Enabled && !av.checkDoubleClose(idToClose) {
+					ok = false
+				}
+
+				if av.settings.Checks.IgnoredCloseErrors.Enabled && !av.checkIgnoredCloseErrors(g, idToClose) {
+					ok = false
+				}
 			}
 		}
+	}
 
-		castedStmt, ok := stmt.(*ast.AssignStmt)
-		if !ok {
-			continue
-		}
+	return ok
+}
 
-		if av.hasGlobalCloserInAssignment(castedStmt.Lhs) {
-			continue
+func (av *AssignVisitor) checkUnassignedCloser(node ast.Node) bool {
+	switch castedStmt := node.(type) {
+	case *ast.ExprStmt:
+		call, ok := castedStmt.X.(*ast.CallExpr)
+		if ok && av.callReturnsCloser(call) {
+			av.pass.Reportf(call.Pos(), "return value won't be closed because it wasn't assigned") // FIXME: improve message
+			return false
 		}
-
-		if len(castedStmt.Rhs) == 1 {
-			posListToClose = append(posListToClose, av.handleAssignment(castedStmt.Lhs, castedStmt.Rhs[0])...)
-		} else {
-			posListToClose = append(posListToClose, av.handleMultiAssignment(castedStmt.Lhs, castedStmt.Rhs)...)
+	case *ast.DeferStmt:
+		if av.callReturnsCloser(castedStmt.Call) {
+			av.pass.Reportf(castedStmt.Call.Pos(), "return value won't be closed because it's on defer statement") // FIXME: improve message
+			return false
 		}
-	}
-
-	for _, idToClose := range posListToClose {
-		if !idToClose.wasClosedOrReturned {
-			av.pass.Reportf(idToClose.parent.Pos(), "%s (%s) was not closed", idToClose.name, idToClose.typeName)
+	case *ast.GoStmt:
+		if av.callReturnsCloser(castedStmt.Call) {
+			av.pass.Reportf(castedStmt.Call.Pos(), "return value won't be closed because it's on go statement") // FIXME: improve message
 			return false
 		}
 	}
@@ -192,6 +196,23 @@ func (av *AssignVisitor) traverse(stmts []ast.Stmt) bool {
 	return true
 }
 
+func (av *AssignVisitor) closersAssignedIn(node ast.Node) []*posToClose {
+	castedStmt, ok := node.(*ast.AssignStmt)
+	if !ok {
+		return nil
+	}
+
+	if av.hasGlobalCloserInAssignment(castedStmt.Lhs) {
+		return nil
+	}
+
+	if len(castedStmt.Rhs) == 1 {
+		return av.handleAssignment(castedStmt.Lhs, castedStmt.Rhs[0])
+	}
+
+	return av.handleMultiAssignment(castedStmt.Lhs, castedStmt.Rhs)
+}
+
 func (av *AssignVisitor) hasGlobalCloserInAssignment(lhs []ast.Expr) bool {
 	for i := 0; i < len(lhs); i++ {
 		assignedID, ok := lhs[i].(*ast.Ident)
@@ -207,40 +228,53 @@ func (av *AssignVisitor) hasGlobalCloserInAssignment(lhs []ast.Expr) bool {
 	return false
 }
 
-func (av *AssignVisitor) returnsOrClosesIDOnExpression(idToClose posToClose, expr ast.Expr) bool {
+func (av *AssignVisitor) returnsOrClosesIDOnExpression(idToClose *posToClose, expr ast.Expr) bool {
 	switch cExpr := expr.(type) {
 	case *ast.Ident:
+		if lit := av.closureOf(cExpr); lit != nil {
+			return av.closureReleases(idToClose, lit)
+		}
+
 		return av.getKnownCloserFromIdent(cExpr) != nil
 	case *ast.FuncLit:
-		return av.traverse(cExpr.Body.List)
+		return av.closureReleases(idToClose, cExpr)
 	case *ast.CallExpr:
-		return av.callsToKnownCloser(idToClose.pos, cExpr)
+		return av.callsToKnownCloser(idToClose, cExpr)
 	case *ast.SelectorExpr:
 		return av.getKnownCloserFromSelector(cExpr) != nil
+	case *ast.BinaryExpr:
+		// comparing a function, like `cleanup != nil`, doesn't call it
+		_, xIsIdent := cExpr.X.(*ast.Ident)
+		_, yIsIdent := cExpr.Y.(*ast.Ident)
+
+		return !xIsIdent && av.returnsOrClosesIDOnExpression(idToClose, cExpr.X) ||
+			!yIsIdent && av.returnsOrClosesIDOnExpression(idToClose, cExpr.Y)
+	case *ast.ParenExpr:
+		return av.returnsOrClosesIDOnExpression(idToClose, cExpr.X)
 	}
 
 	return false
 }
 
-func (av *AssignVisitor) returnsOrClosesID(idToClose posToClose, stmt ast.Stmt) bool {
-	switch castedStmt := stmt.(type) {
+func (av *AssignVisitor) returnsOrClosesID(idToClose *posToClose, node ast.Node) bool {
+	if av.storesInCollection(idToClose, node) {
+		return true
+	}
+
+	switch castedStmt := node.(type) {
 	case *ast.ReturnStmt:
 		for _, res := range castedStmt.Results {
-			if av.isPosInExpression(idToClose.pos, res) {
-				return true
-			}
-
-			if idToClose.pos != idToClose.parent.Pos() && av.isPosInExpression(idToClose.parent.Pos(), res) {
+			if av.isPosInExpression(idToClose, res) || av.returnsOrClosesIDOnExpression(idToClose, res) {
 				return true
 			}
 		}
 
 	case *ast.DeferStmt:
-		if av.callsToKnownCloser(idToClose.pos, castedStmt.Call) {
+		if av.callsToKnownCloser(idToClose, castedStmt.Call) {
 			return true
 		}
 	case *ast.GoStmt:
-		if av.callsToKnownCloser(idToClose.pos, castedStmt.Call) {
+		if av.callsToKnownCloser(idToClose, castedStmt.Call) {
 			return true
 		}
 	case *ast.ExprStmt:
@@ -249,45 +283,66 @@ func (av *AssignVisitor) returnsOrClosesID(idToClose posToClose, stmt ast.Stmt)
 			return false
 		}
 
-		if av.callReturnsCloser(call) {
-			av.pass.Reportf(call.Pos(), "return value won't be closed because it wasn't assigned") // FIXME: improve message
-			return false
-		}
-
 		for _, arg := range call.Args {
 			if av.returnsOrClosesIDOnExpression(idToClose, arg) {
 				return true
 			}
 		}
 
-		if av.callsToKnownCloser(idToClose.pos, call) {
+		if av.callsToKnownCloser(idToClose, call) {
 			return true
 		}
 
 	case *ast.AssignStmt:
+		if idToClose.storeReleases && av.storesID(idToClose, castedStmt) {
+			return true
+		}
+
 		for _, exp := range castedStmt.Rhs {
 			if call, ok := exp.(*ast.CallExpr); ok {
-				if av.callsToKnownCloser(idToClose.pos, call) {
+				if av.callsToKnownCloser(idToClose, call) {
 					return true
 				}
 			}
 		}
 
-	case *ast.IfStmt:
-		if av.returnsOrClosesID(idToClose, castedStmt.Init) {
-			return true
+	case ast.Expr:
+		// conditions of if, for and switch statements
+		return av.returnsOrClosesIDOnExpression(idToClose, castedStmt)
+	}
+
+	return false
+}
+
+// storesID checks whether the closer is stored in another value by the assignment, like `c.conn = conn`
+func (av *AssignVisitor) storesID(idToClose *posToClose, assign *ast.AssignStmt) bool {
+	for i, rhs := range assign.Rhs {
+		if i < len(assign.Lhs) && isBlank(assign.Lhs[i]) {
+			continue
 		}
 
-		for _, stmt := range castedStmt.Body.List {
-			if av.returnsOrClosesID(idToClose, stmt) {
-				return true
+		if !av.isPosInExpression(idToClose, rhs) {
+			continue
+		}
+
+		if sel, ok := assign.Lhs[i].(*ast.SelectorExpr); ok && len(assign.Lhs) == len(assign.Rhs) {
+			if field, ok := av.pass.TypesInfo.ObjectOf(sel.Sel).(*types.Var); ok && field.IsField() {
+				av.checkStoredInField(idToClose, av.pass.TypesInfo.TypeOf(sel.X), field, rhs)
 			}
 		}
+
+		return true
 	}
 
 	return false
 }
 
+func isBlank(expr ast.Expr) bool {
+	id, ok := expr.(*ast.Ident)
+
+	return ok && id.Name == "_"
+}
+
 func (av *AssignVisitor) handleMultiAssignment(lhs []ast.Expr, rhs []ast.Expr) []*posToClose {
 	posListToClose := make([]*posToClose, 0)
 
@@ -308,23 +363,7 @@ func (av *AssignVisitor) handleMultiAssignment(lhs []ast.Expr, rhs []ast.Expr) [
 			continue
 		}
 
-		if len(returnVars[0].fields) == 0 {
-			posListToClose = append(posListToClose, &posToClose{
-				parent:   id,
-				name:     id.Name,
-				typeName: returnVars[0].typeName,
-				pos:      id.Pos(),
-			})
-		}
-
-		for _, field := range returnVars[0].fields {
-			posListToClose = append(posListToClose, &posToClose{
-				parent:   id,
-				name:     id.Name + "." + field.name,
-				typeName: field.typeName,
-				pos:      field.pos,
-			})
-		}
+		posListToClose = append(posListToClose, av.newPosListToClose(id, returnVars[0], nil)...)
 	}
 
 	return posListToClose
@@ -338,6 +377,7 @@ func (av *AssignVisitor) handleAssignment(lhs []ast.Expr, rhs ast.Expr) []*posTo
 
 	returnVars := av.returnsThatAreClosers(call)
 	posListToClose := make([]*posToClose, 0, len(returnVars))
+	errObj := av.findErrorInAssignment(lhs)
 
 	for i := 0; i < len(lhs); i++ {
 		id, ok := lhs[i].(*ast.Ident)
@@ -349,23 +389,7 @@ func (av *AssignVisitor) handleAssignment(lhs []ast.Expr, rhs ast.Expr) []*posTo
 			continue
 		}
 
-		if len(returnVars[i].fields) == 0 {
-			posListToClose = append(posListToClose, &posToClose{
-				parent:   id,
-				name:     id.Name,
-				typeName: returnVars[i].typeName,
-				pos:      id.Pos(),
-			})
-		}
-
-		for _, field := range returnVars[i].fields {
-			posListToClose = append(posListToClose, &posToClose{
-				parent:   id,
-				name:     id.Name + "." + field.name,
-				typeName: field.typeName,
-				pos:      field.pos,
-			})
-		}
+		posListToClose = append(posListToClose, av.newPosListToClose(id, returnVars[i], errObj)...)
 	}
 
 	// TODO: check that Rhs is not a call to a known av.closerFuncs
@@ -373,6 +397,57 @@ func (av *AssignVisitor) handleAssignment(lhs []ast.Expr, rhs ast.Expr) []*posTo
 	return posListToClose
 }
 
+func (av *AssignVisitor) newPosListToClose(id *ast.Ident, rv returnVar, errObj types.Object) []*posToClose {
+	obj := av.pass.TypesInfo.ObjectOf(id)
+
+	if len(rv.fields) == 0 {
+		return []*posToClose{{
+			parent:   id,
+			name:     id.Name,
+			typeName: rv.typeName,
+			obj:      obj,
+			errObj:   errObj,
+			rule:     rv.rule,
+		}}
+	}
+
+	posListToClose := make([]*posToClose, 0, len(rv.fields))
+
+	for _, field := range rv.fields {
+		posListToClose = append(posListToClose, &posToClose{
+			parent:   id,
+			name:     id.Name + "." + field.name,
+			typeName: field.typeName,
+			obj:      obj,
+			field:    field.obj,
+			errObj:   errObj,
+			rule:     field.rule,
+		})
+	}
+
+	return posListToClose
+}
+
+// findErrorInAssignment finds the error variable that is assigned along with a closer, like err in
+// `res, err := http.Get(url)`
+func (av *AssignVisitor) findErrorInAssignment(lhs []ast.Expr) types.Object {
+	errorType := types.Universe.Lookup("error").Type()
+
+	for _, expr := range lhs {
+		id, ok := expr.(*ast.Ident)
+		if !ok {
+			continue
+		}
+
+		obj := av.pass.TypesInfo.ObjectOf(id)
+		if obj != nil && id.Name != "_" && types.Identical(obj.Type(), errorType) {
+			return obj
+		}
+	}
+
+	return nil
+}
+
 func (av *AssignVisitor) callReturnsCloser(call *ast.CallExpr) bool {
 	for _, returnVar := range av.returnsThatAreClosers(call) {
 		if returnVar.needsClosing {
@@ -384,27 +459,33 @@ func (av *AssignVisitor) callReturnsCloser(call *ast.CallExpr) bool {
 }
 
 func (av *AssignVisitor) returnsThatAreClosers(call *ast.CallExpr) []returnVar {
-	if fn, ok := call.Fun.(*ast.SelectorExpr); ok && fn.Sel.Name == "NopCloser" {
-		o, ok := fn.X.(*ast.Ident)
-		if ok && (o.Name == "ioutil" || o.Name == "io") {
-			return []returnVar{{}}
+	returnVars := av.returnVarsOf(call)
+	callee, _ := typeutil.Callee(av.pass.TypesInfo, call).(*types.Func)
+
+	for i := range returnVars {
+		if returnsBorrowedCloser(av.pass, callee, i) || av.isNonOwningWrapper(call) {
+			returnVars[i] = returnVar{}
 		}
 	}
 
+	return returnVars
+}
+
+func (av *AssignVisitor) returnVarsOf(call *ast.CallExpr) []returnVar {
 	switch t := av.pass.TypesInfo.Types[call].Type.(type) {
 	case *types.Named:
-		return []returnVar{av.newReturnVar(t)}
+		return []returnVar{av.settings.rules.newReturnVar(t)}
 	case *types.Pointer:
-		return []returnVar{av.newReturnVar(t)}
+		return []returnVar{av.settings.rules.newReturnVar(t)}
 	case *types.Tuple:
 		s := make([]returnVar, t.Len())
 
 		for i := 0; i < t.Len(); i++ {
 			switch et := t.At(i).Type().(type) {
 			case *types.Named:
-				s[i] = av.newReturnVar(et)
+				s[i] = av.settings.rules.newReturnVar(et)
 			case *types.Pointer:
-				s[i] = av.newReturnVar(et)
+				s[i] = av.settings.rules.newReturnVar(et)
 			}
 		}
 
@@ -421,14 +502,10 @@ func (av *AssignVisitor) getKnownCloserFromIdent(id *ast.Ident) *ioCloserFunc {
 	}
 
 	fn, ok := av.closerFuncs[fndecl]
-	if !ok {
+	if !ok || !fn.isCloser {
 		return nil
 	}
 
-	if av.pass.ImportObjectFact(fndecl, fn) && fn.isCloser {
-		return fn
-	}
-
 	return fn
 }
 
@@ -436,14 +513,6 @@ func (av *AssignVisitor) getKnownCloserFromSelector(sel *ast.SelectorExpr) *ioCl
 	var knownCloser *ioCloserFunc
 
 	av.visitSelectors(sel, func(id *ast.Ident) bool {
-		if id.Name == "Close" { // TODO: check that reciever is a io.Closer
-			knownCloser = &ioCloserFunc{
-				isCloser: true,
-			} // this is a hack to mark "Close" as a known closer
-
-			return false
-		}
-
 		if fn := av.getKnownCloserFromIdent(id); fn != nil {
 			knownCloser = fn
 
@@ -466,106 +535,170 @@ func (av *AssignVisitor) visitSelectors(sel *ast.SelectorExpr, cb func(id *ast.I
 	}
 }
 
-func (av *AssignVisitor) findKnownReceiverFromCall(pos token.Pos, call *ast.CallExpr) *ioCloserFunc {
-	fndecl, _ := typeutil.Callee(av.pass.TypesInfo, call).(*types.Func)
-	if fndecl == nil {
-		return nil
+func (av *AssignVisitor) callsToKnownCloser(idToClose *posToClose, call *ast.CallExpr) bool {
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok && idToClose.rule.isReleaseMethod(sel.Sel.Name) &&
+		av.isCloserExpr(idToClose, sel.X) {
+		return true
 	}
 
-	fn := &ioCloserFunc{}
-	av.pass.ImportObjectFact(fndecl, fn)
-
-	return fn
-}
+	if len(idToClose.rule.methods) == 0 && av.isCloserExpr(idToClose, call.Fun) {
+		return true
+	}
 
-func (av *AssignVisitor) callsToKnownCloser(pos token.Pos, call *ast.CallExpr) bool {
 	fndecl, _ := typeutil.Callee(av.pass.TypesInfo, call).(*types.Func)
 	fn := &ioCloserFunc{}
+	isCloser := func(expr ast.Expr) bool { return av.isPosInExpression(idToClose, expr) }
 
-	if fndecl != nil && av.pass.ImportObjectFact(fndecl, fn) && fn != nil {
-		return fn.isCloser
+	if fndecl != nil && av.pass.ImportObjectFact(fndecl, fn) && fn.releasesArg(av.pass.TypesInfo, call, fndecl, isCloser) {
+		return true
+	}
+
+	if fndecl != nil && av.settings.isRegistrationFunction(fndecl.FullName()) && av.registersRelease(idToClose, fndecl, call.Args) {
+		return true
+	}
+
+	if lit := av.calledClosure(call); lit != nil {
+		return av.closureCallReleases(idToClose, lit, call.Args)
+	}
+
+	if !av.isPosInAnyExpression(idToClose, call.Args) {
+		return false
+	}
+
+	if fndecl != nil && av.settings.isReleaseFunction(fndecl.FullName()) {
+		return true
+	}
+
+	if fndecl != nil && av.pass.ImportObjectFact(fndecl, fn) {
+		return false
 	}
 
 	switch castedFun := call.Fun.(type) {
 	case *ast.CallExpr:
-		return av.callsToKnownCloser(pos, castedFun)
+		return av.callsToKnownCloser(idToClose, castedFun)
 	case *ast.Ident:
 		return av.getKnownCloserFromIdent(castedFun) != nil
 	case *ast.SelectorExpr:
 		return av.getKnownCloserFromSelector(castedFun) != nil
-	case *ast.FuncLit:
-		return av.traverse(castedFun.Body.List)
 	}
 
-	// TODO: check that call.Args match with the params that are received and closed by "fn"
+	return false
+}
 
-	return fn.isCloser
+func (av *AssignVisitor) isPosInAnyExpression(idToClose *posToClose, exprs []ast.Expr) bool {
+	for _, expr := range exprs {
+		if av.isPosInExpression(idToClose, expr) {
+			return true
+		}
+	}
+
+	return false
 }
 
-func (av *AssignVisitor) isPosInExpression(pos token.Pos, expr ast.Expr) bool {
+// isPosInExpression checks whether the closer, or the value that holds it, is used in the given expression
+func (av *AssignVisitor) isPosInExpression(idToClose *posToClose, expr ast.Expr) bool {
 	switch castedExpr := expr.(type) {
+	case *ast.ParenExpr:
+		return av.isPosInExpression(idToClose, castedExpr.X)
 	case *ast.UnaryExpr:
-		return av.isPosInExpression(pos, castedExpr.X)
+		return av.isPosInExpression(idToClose, castedExpr.X)
+	case *ast.StarExpr:
+		return av.isPosInExpression(idToClose, castedExpr.X)
 	case *ast.CallExpr:
-		return av.findKnownReceiverFromCall(pos, castedExpr) != nil
+		return av.isPosInAnyExpression(idToClose, castedExpr.Args)
 	case *ast.Ident:
-		return av.isIdentInPos(castedExpr, pos)
+		return idToClose.obj != nil && av.isObject(castedExpr, idToClose.obj)
 	case *ast.SelectorExpr:
-		wasFound := false
+		return av.isCloserExpr(idToClose, castedExpr)
+	case *ast.CompositeLit:
+		for i, elt := range castedExpr.Elts {
+			if !av.isPosInExpression(idToClose, elt) {
+				continue
+			}
 
-		av.visitSelectors(castedExpr, func(id *ast.Ident) bool {
-			if av.isIdentInPos(id, pos) {
-				wasFound = true
-				return false
+			if field := av.compositeField(castedExpr, i); field != nil {
+				av.checkStoredInField(idToClose, av.pass.TypesInfo.TypeOf(castedExpr), field, elt)
 			}
 
 			return true
-		})
-
-		return wasFound
-	case *ast.CompositeLit:
-		if castedExpr.Elts == nil {
-			break
-		}
-
-		for _, e := range castedExpr.Elts {
-			if av.isPosInExpression(pos, e) {
-				return true
-			}
 		}
 	case *ast.KeyValueExpr:
-		// FIXME: this is not 100% accurate because we haven't checked that the Close is called in the future
-		if av.isPosInExpression(pos, castedExpr.Value) {
-			return true
-		}
+		return av.isPosInExpression(idToClose, castedExpr.Value)
 	}
 
 	return false
 }
 
-func (av *AssignVisitor) isIdentInPos(id *ast.Ident, pos token.Pos) bool {
-	if pos == id.Pos() {
-		return true
+// compositeField returns the struct field set by the i-th element of the composite literal
+func (av *AssignVisitor) compositeField(lit *ast.CompositeLit, i int) *types.Var {
+	_, str := wrapperStruct(av.pass.TypesInfo.TypeOf(lit))
+	if str == nil {
+		return nil
 	}
 
-	decl := av.pass.TypesInfo.ObjectOf(id)
+	if kv, ok := lit.Elts[i].(*ast.KeyValueExpr); ok {
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			return nil
+		}
 
-	return decl.Pos() == pos
+		field, _ := av.pass.TypesInfo.ObjectOf(key).(*types.Var)
+
+		return field
+	}
+
+	if i < str.NumFields() {
+		return str.Field(i)
+	}
+
+	return nil
 }
 
-func (av *AssignVisitor) isExprEqualToIdent(id *ast.Ident, x ast.Expr) bool {
-	xIdent, ok := x.(*ast.Ident)
-	if !ok {
+// checkStoredInField reports the closers stored in a field of a resource type that isn't released by the release
+// method of the type. The closer is still considered released because it's the type that has to be fixed.
+func (av *AssignVisitor) checkStoredInField(idToClose *posToClose, owner types.Type, field *types.Var, node ast.Node) {
+	named, _ := wrapperStruct(owner)
+	if named == nil || av.reportedFields[node.Pos()] {
+		return
+	}
+
+	fact := &releasedFields{}
+	if !av.pass.ImportObjectFact(named.Obj(), fact) || fact.releases(field) {
+		return
+	}
+
+	av.reportedFields[node.Pos()] = true
+	av.pass.Reportf(node.Pos(), "%s (%s) is stored in %s.%s but %s.%s doesn't release it", idToClose.name,
+		idToClose.typeName, named.Obj().Name(), field.Name(), named.Obj().Name(), fact.method)
+}
+
+// isCloserExpr checks whether the expression is exactly the closer, like `res.Body` for the body of `res`
+func (av *AssignVisitor) isCloserExpr(idToClose *posToClose, expr ast.Expr) bool {
+	if idToClose.obj == nil {
 		return false
 	}
 
-	if id.NamePos == xIdent.NamePos {
-		return true
+	expr = astutil.Unparen(expr)
+
+	if idToClose.field == nil {
+		return av.isObject(expr, idToClose.obj)
+	}
+
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
 	}
 
-	decl := av.pass.TypesInfo.ObjectOf(xIdent)
+	return av.pass.TypesInfo.ObjectOf(sel.Sel) == idToClose.field && av.isObject(sel.X, idToClose.obj)
+}
+
+func (av *AssignVisitor) isObject(expr ast.Expr, obj types.Object) bool {
+	id, ok := astutil.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return false
+	}
 
-	return decl.Pos() == id.Pos()
+	return av.pass.TypesInfo.ObjectOf(id) == obj
 }
 
 func (av *AssignVisitor) shouldIgnoreGlobalVariable(id *ast.Ident) bool {
@@ -0,0 +1,229 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/cfg"
+)
+
+// closureReleases checks whether the function literal releases the closer it captures, that is, whether its body
+// references the variable that holds the closer and releases it on every path
+func (av *AssignVisitor) closureReleases(idToClose *posToClose, lit *ast.FuncLit) bool {
+	if av.releasingClosures[lit] {
+		// the closure calls itself
+		return false
+	}
+
+	av.releasingClosures[lit] = true
+	defer delete(av.releasingClosures, lit)
+
+	return av.closureReleasesOnEveryPath(idToClose, lit)
+}
+
+// closureCallReleases checks whether calling the function literal with the given arguments releases the closer,
+// either because the closure captures it or because it's passed in the arguments, like
+// `go func(body io.Closer) { body.Close() }(res.Body)`
+func (av *AssignVisitor) closureCallReleases(idToClose *posToClose, lit *ast.FuncLit, args []ast.Expr) bool {
+	if av.closureReleases(idToClose, lit) {
+		return true
+	}
+
+	params := []*ast.Ident{}
+	for _, field := range lit.Type.Params.List {
+		params = append(params, field.Names...)
+	}
+
+	for i, arg := range args {
+		if i >= len(params) {
+			break
+		}
+
+		param := av.pass.TypesInfo.Defs[params[i]]
+		if param == nil {
+			continue
+		}
+
+		inner := *idToClose
+
+		switch {
+		case idToClose.field != nil && av.isObject(arg, idToClose.obj):
+			// the value that holds the closer is passed, like res for res.Body
+			inner.obj = param
+		case av.isCloserExpr(idToClose, arg):
+			inner.obj, inner.field = param, nil
+		default:
+			continue
+		}
+
+		if av.closureReleases(&inner, lit) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// calledClosure returns the function literal called by the call, either directly like `func() { ... }()` or through
+// the variable it's stored in like `cleanup()`
+func (av *AssignVisitor) calledClosure(call *ast.CallExpr) *ast.FuncLit {
+	switch fun := astutil.Unparen(call.Fun).(type) {
+	case *ast.FuncLit:
+		return fun
+	case *ast.Ident:
+		return av.closureOf(fun)
+	}
+
+	return nil
+}
+
+// closureOf returns the function literal assigned to the variable when it's declared, like `cleanup` in
+// `cleanup := func() { f.Close() }`
+func (av *AssignVisitor) closureOf(id *ast.Ident) *ast.FuncLit {
+	return av.closures[av.pass.TypesInfo.ObjectOf(id)]
+}
+
+// indexClosures records, in a single walk of the package, the function literals assigned to the local variables
+// when they are declared and the variables that are invoked, so looking them up doesn't walk the files again
+func (av *AssignVisitor) indexClosures(inspect *inspector.Inspector) {
+	av.closures = map[types.Object]*ast.FuncLit{}
+	av.invokedVars = map[types.Object]bool{}
+
+	nodeFilter := []ast.Node{
+		(*ast.AssignStmt)(nil),
+		(*ast.ValueSpec)(nil),
+		(*ast.Ident)(nil),
+	}
+
+	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			if len(node.Lhs) != len(node.Rhs) {
+				return true
+			}
+
+			for i, lhs := range node.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok {
+					av.recordClosure(av.pass.TypesInfo.Defs[id], node.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range node.Names {
+				if i < len(node.Values) {
+					av.recordClosure(av.pass.TypesInfo.Defs[name], node.Values[i])
+				}
+			}
+		case *ast.Ident:
+			obj := av.pass.TypesInfo.Uses[node]
+			if obj != nil && len(stack) > 1 && av.invokes(node, stack[len(stack)-2]) {
+				av.invokedVars[obj] = true
+			}
+		}
+
+		return true
+	})
+}
+
+// recordClosure records the function literal assigned to the local variable when it's declared
+func (av *AssignVisitor) recordClosure(obj types.Object, value ast.Expr) {
+	v, ok := obj.(*types.Var)
+	if !ok || v.Pkg() == nil || v.Parent() == v.Pkg().Scope() {
+		return
+	}
+
+	if lit, ok := astutil.Unparen(value).(*ast.FuncLit); ok {
+		av.closures[v] = lit
+	}
+}
+
+// invokes checks whether the use of a variable can call the closure stored in it, that is, whether the variable is
+// called or passed somewhere else, instead of only being compared, discarded or assigned
+func (av *AssignVisitor) invokes(id *ast.Ident, parent ast.Node) bool {
+	switch parent := parent.(type) {
+	case *ast.BinaryExpr:
+		return false
+	case *ast.AssignStmt:
+		return !av.isBlankAssignment(parent, id) && !av.isAssigned(parent, id)
+	}
+
+	return true
+}
+
+// checkUncalledClosures reports the closures that release the closer but are stored in a variable that is never
+// called, so the closer is never released by them
+func (av *AssignVisitor) checkUncalledClosures(g *cfg.CFG, idToClose *posToClose) bool {
+	if idToClose.obj == nil {
+		return true
+	}
+
+	ok := true
+	region := av.reachableBlocks(idToClose.block)
+
+	for _, block := range g.Blocks {
+		if !region[block] {
+			continue
+		}
+
+		for i, node := range block.Nodes {
+			if block == idToClose.block && i <= idToClose.index {
+				continue
+			}
+
+			assign, isAssign := node.(*ast.AssignStmt)
+			if !isAssign || len(assign.Lhs) != len(assign.Rhs) {
+				continue
+			}
+
+			for j, rhs := range assign.Rhs {
+				lit, isLit := astutil.Unparen(rhs).(*ast.FuncLit)
+				id, isIdent := assign.Lhs[j].(*ast.Ident)
+
+				if !isLit || !isIdent || isBlank(id) || av.isInvoked(av.pass.TypesInfo.ObjectOf(id)) ||
+					av.reportedCloses[lit.Pos()] || !av.closureReleases(idToClose, lit) {
+					continue
+				}
+
+				ok = false
+				av.reportedCloses[lit.Pos()] = true
+
+				av.pass.Reportf(lit.Pos(), "%s (%s) is %s by the closure stored in %s, but %s is never called",
+					idToClose.name, idToClose.typeName, idToClose.rule.action, id.Name, id.Name)
+			}
+		}
+	}
+
+	return ok
+}
+
+// isInvoked checks whether the closure stored in the variable can be called
+func (av *AssignVisitor) isInvoked(obj types.Object) bool {
+	return av.invokedVars[obj]
+}
+
+// isBlankAssignment checks whether the expression is assigned to the blank identifier, like `_ = cleanup`
+func (av *AssignVisitor) isBlankAssignment(assign *ast.AssignStmt, expr ast.Expr) bool {
+	for i, rhs := range assign.Rhs {
+		if rhs == expr && i < len(assign.Lhs) && len(assign.Lhs) == len(assign.Rhs) {
+			return isBlank(assign.Lhs[i])
+		}
+	}
+
+	return false
+}
+
+// isAssigned checks whether the identifier is assigned a new value by the assignment
+func (av *AssignVisitor) isAssigned(assign *ast.AssignStmt, id *ast.Ident) bool {
+	for _, lhs := range assign.Lhs {
+		if lhs == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,226 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// storesInCollection checks whether the node sends the closer over a channel or stores it in a slice or a map, like
+// `ch <- f`, `files = append(files, f)` or `conns[addr] = conn`. The closer escapes to the collection, so it's
+// released unless the require-loop policy is configured and no loop over the collection releases its elements.
+func (av *AssignVisitor) storesInCollection(idToClose *posToClose, node ast.Node) bool {
+	switch castedNode := node.(type) {
+	case *ast.SendStmt:
+		if av.isPosInExpression(idToClose, castedNode.Value) {
+			return av.escapesTo(idToClose, castedNode.Chan, castedNode.Value, node)
+		}
+	case *ast.AssignStmt:
+		for i, rhs := range castedNode.Rhs {
+			if index, ok := castedNode.Lhs[i].(*ast.IndexExpr); ok && len(castedNode.Lhs) == len(castedNode.Rhs) &&
+				av.isPosInExpression(idToClose, rhs) {
+				return av.escapesTo(idToClose, index.X, rhs, node)
+			}
+
+			call, ok := astutil.Unparen(rhs).(*ast.CallExpr)
+			if !ok || !av.isAppend(call) {
+				continue
+			}
+
+			for _, arg := range call.Args[1:] {
+				if av.isPosInExpression(idToClose, arg) {
+					return av.escapesTo(idToClose, call.Args[0], arg, node)
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func (av *AssignVisitor) isAppend(call *ast.CallExpr) bool {
+	id, ok := astutil.Unparen(call.Fun).(*ast.Ident)
+	if !ok || len(call.Args) < 2 {
+		return false
+	}
+
+	builtin, ok := av.pass.TypesInfo.ObjectOf(id).(*types.Builtin)
+
+	return ok && builtin.Name() == "append"
+}
+
+// escapesTo checks the policy for the closer stored in the collection, the collection is trusted to release it unless
+// the require-loop policy is configured
+func (av *AssignVisitor) escapesTo(idToClose *posToClose, collection ast.Expr, elem ast.Expr, node ast.Node) bool {
+	if av.settings.Collections != requireLoopCollections || av.reportedCloses[node.Pos()] {
+		return true
+	}
+
+	obj := av.collectionObject(collection)
+	if obj == nil || av.collectionEscapes(obj, node) || av.releasedInLoop(idToClose, obj, elem, node) {
+		return true
+	}
+
+	av.reportedCloses[node.Pos()] = true
+	av.pass.Reportf(node.Pos(), "%s (%s) is stored in %s, but the elements of %s aren't %s in a loop", idToClose.name,
+		idToClose.typeName, obj.Name(), obj.Name(), idToClose.rule.action)
+
+	// the leak is already reported
+	return true
+}
+
+// collectionObject returns the variable or the field that holds the collection, like `files` or `p.conns`
+func (av *AssignVisitor) collectionObject(expr ast.Expr) types.Object {
+	switch castedExpr := astutil.Unparen(expr).(type) {
+	case *ast.Ident:
+		return av.pass.TypesInfo.ObjectOf(castedExpr)
+	case *ast.SelectorExpr:
+		return av.pass.TypesInfo.ObjectOf(castedExpr.Sel)
+	}
+
+	return nil
+}
+
+// isLocalCollection checks whether the collection is a local variable of a function of the package
+func (av *AssignVisitor) isLocalCollection(collection types.Object) bool {
+	return collection.Pkg() == av.pass.Pkg && collection.Parent() != nil &&
+		collection.Parent() != collection.Pkg().Scope()
+}
+
+// enclosingFuncDecl returns the function declaration that contains the node
+func (av *AssignVisitor) enclosingFuncDecl(node ast.Node) *ast.FuncDecl {
+	path := av.findPath(node)
+
+	for i := len(path) - 1; i >= 0; i-- {
+		if fdecl, ok := path[i].(*ast.FuncDecl); ok {
+			return fdecl
+		}
+	}
+
+	return nil
+}
+
+// collectionEscapes checks whether the local collection is returned or stored in a field or a global variable by the
+// function that stores the closer, like `return files` or `p.files = files`. The elements are released along with the
+// collection by whoever receives it, the same as a returned closer.
+func (av *AssignVisitor) collectionEscapes(collection types.Object, node ast.Node) bool {
+	if !av.isLocalCollection(collection) {
+		return false
+	}
+
+	fdecl := av.enclosingFuncDecl(node)
+	if fdecl == nil {
+		return false
+	}
+
+	escapes := false
+
+	ast.Inspect(fdecl, func(n ast.Node) bool {
+		switch castedNode := n.(type) {
+		case *ast.ReturnStmt:
+			for _, result := range castedNode.Results {
+				if av.isObject(result, collection) {
+					escapes = true
+				}
+			}
+		case *ast.AssignStmt:
+			if len(castedNode.Lhs) != len(castedNode.Rhs) {
+				break
+			}
+
+			for i, rhs := range castedNode.Rhs {
+				if av.isObject(rhs, collection) && av.isFieldOrGlobal(castedNode.Lhs[i]) {
+					escapes = true
+				}
+			}
+		}
+
+		return !escapes
+	})
+
+	return escapes
+}
+
+// isFieldOrGlobal checks whether the expression is a field, like `p.files`, or a package variable
+func (av *AssignVisitor) isFieldOrGlobal(expr ast.Expr) bool {
+	switch castedExpr := astutil.Unparen(expr).(type) {
+	case *ast.SelectorExpr:
+		return true
+	case *ast.Ident:
+		obj := av.pass.TypesInfo.ObjectOf(castedExpr)
+
+		return obj != nil && obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope()
+	}
+
+	return false
+}
+
+// releasedInLoop checks whether there's a range loop over the collection that releases its elements. The loops are
+// looked for in the function that stores the closer when the collection is a local variable, or in the whole
+// package otherwise.
+func (av *AssignVisitor) releasedInLoop(idToClose *posToClose, collection types.Object, elem ast.Expr, node ast.Node) bool {
+	scopes := []ast.Node{}
+
+	if av.isLocalCollection(collection) {
+		if fdecl := av.enclosingFuncDecl(node); fdecl != nil {
+			scopes = append(scopes, fdecl)
+		}
+	} else {
+		for _, file := range av.pass.Files {
+			scopes = append(scopes, file)
+		}
+	}
+
+	released := false
+
+	for _, scope := range scopes {
+		ast.Inspect(scope, func(n ast.Node) bool {
+			rangeStmt, ok := n.(*ast.RangeStmt)
+			if !ok || released {
+				return !released
+			}
+
+			if av.collectionObject(rangeStmt.X) == collection && av.loopReleases(idToClose, rangeStmt, elem) {
+				released = true
+			}
+
+			return !released
+		})
+	}
+
+	return released
+}
+
+// loopReleases checks whether the body of the loop releases the element of the collection, like
+// `for _, f := range files { f.Close() }`
+func (av *AssignVisitor) loopReleases(idToClose *posToClose, rangeStmt *ast.RangeStmt, elem ast.Expr) bool {
+	elemVar := rangeStmt.Value
+	if _, isChan := av.pass.TypesInfo.TypeOf(rangeStmt.X).Underlying().(*types.Chan); isChan {
+		elemVar = rangeStmt.Key
+	}
+
+	id, ok := elemVar.(*ast.Ident)
+	if !ok || isBlank(id) {
+		return false
+	}
+
+	inner := *idToClose
+	inner.obj = av.pass.TypesInfo.ObjectOf(id)
+
+	if av.isCloserExpr(idToClose, elem) {
+		inner.field = nil
+	}
+
+	released := false
+
+	ast.Inspect(rangeStmt.Body, func(n ast.Node) bool {
+		if stmt, ok := n.(ast.Stmt); ok && !released && av.returnsOrClosesID(&inner, stmt) {
+			released = true
+		}
+
+		return !released
+	})
+
+	return released
+}
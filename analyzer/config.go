@@ -0,0 +1,362 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	trustCollections       = "trust"
+	requireLoopCollections = "require-loop"
+)
+
+// Config is the configuration that can be loaded from a YAML or JSON file with the -config flag
+type Config struct {
+	// Resources are the types that have to be released, along with the default ones
+	Resources []Resource `json:"resources" yaml:"resources"`
+	// ReleaseFunctions are functions that release the resources they receive, like
+	// `github.com/dcu/closecheck/samples/src/testhelper.CloseWithDefer`. Methods are written like
+	// `(*example.com/pool.Pool).Put`.
+	ReleaseFunctions []string `json:"release_functions" yaml:"release_functions"`
+	// RegistrationFunctions are functions that register the resources they receive to be released later, along with
+	// the default ones like `(*testing.common).Cleanup`. Their arguments can be the resources, the release methods of
+	// the resources like `f.Close`, or closures that release them.
+	RegistrationFunctions []string `json:"registration_functions" yaml:"registration_functions"`
+	// NonOwningWrappers are functions that return closers that don't have to be released, along with the default ones
+	// like `io.NopCloser`
+	NonOwningWrappers []string `json:"non_owning_wrappers" yaml:"non_owning_wrappers"`
+	// OwningWrappers are functions that wrap the closers they receive, along with the default ones like
+	// `bufio.NewReader`. The closer is released on the paths where the result of the wrapper is released,
+	// returned or stored.
+	OwningWrappers []string `json:"owning_wrappers" yaml:"owning_wrappers"`
+	// Writers are the resources whose Close errors report failed writes, along with the default ones. They are types
+	// like `compress/gzip.Writer`, or functions that return them like `os.Create`.
+	Writers []string `json:"writers" yaml:"writers"`
+	// Collections is the policy for the closers sent over channels or stored in slices and maps: "trust", the
+	// default, considers them released, "require-loop" requires a loop over the collection that releases its elements
+	Collections string `json:"collections" yaml:"collections"`
+	// Exclude lists the packages and files that aren't checked
+	Exclude Exclude `json:"exclude" yaml:"exclude"`
+	// Checks enables the optional checks
+	Checks Checks `json:"checks" yaml:"checks"`
+}
+
+// Resource is a type that has to be released by calling one of the Release methods
+type Resource struct {
+	// Type is the qualified name of the type, like `example.com/pool.Conn`
+	Type string `json:"type" yaml:"type"`
+	// Release are the methods that release the resource, calling the value itself releases it when it's empty
+	Release []string `json:"release" yaml:"release"`
+}
+
+// Exclude lists the packages and files that aren't checked
+type Exclude struct {
+	// Packages are import paths, the ones ending with `/...` exclude every package under them
+	Packages []string `json:"packages" yaml:"packages"`
+	// Paths are glob patterns matched against the trailing elements of the file paths, like `*_test.go` or
+	// `generated/*.go`
+	Paths []string `json:"paths" yaml:"paths"`
+}
+
+// Checks are the optional checks, they are disabled by default
+type Checks struct {
+	// DoubleClose reports the closers that are closed twice on the same path
+	DoubleClose DoubleClose `json:"double_close" yaml:"double_close"`
+	// IgnoredCloseErrors reports the writers closed by a defer, an expression statement or a blank assignment that
+	// drops the error
+	IgnoredCloseErrors IgnoredCloseErrors `json:"ignored_close_errors" yaml:"ignored_close_errors"`
+}
+
+// DoubleClose reports the closers that are closed twice on the same path, like `f.Close()` followed by a deferred
+// `f.Close()`
+type DoubleClose struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// AllowCheckedClose accepts closing a writer, like a file created with os.Create, with a defer and also explicitly
+	// to check the error, since the error of Close is the only way to know that the writes failed:
+	//
+	//	defer f.Close()
+	//	...
+	//	return f.Close()
+	AllowCheckedClose bool `json:"allow_checked_close" yaml:"allow_checked_close"`
+}
+
+// IgnoredCloseErrors reports the writers whose Close errors are dropped, like `defer f.Close()` or `_ = f.Close()` for
+// a file created with os.Create. A fix that returns the error is suggested for the defers of the functions with a named
+// error result.
+type IgnoredCloseErrors struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// LoadConfig loads the configuration from a YAML or JSON file
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	config := &Config{}
+
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		err = decoder.Decode(config)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		err = decoder.Decode(config)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported extension %q, it must be .json, .yaml or .yml", path, ext)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	for i, resource := range c.Resources {
+		if _, _, err := splitQualifiedName(resource.Type); err != nil {
+			return fmt.Errorf("resources[%d]: type: %w", i, err)
+		}
+
+		for _, method := range resource.Release {
+			if !token.IsIdentifier(method) {
+				return fmt.Errorf("resources[%d]: release: %q is not a method name", i, method)
+			}
+		}
+	}
+
+	for i, fn := range c.ReleaseFunctions {
+		if err := validateFunctionName(fn); err != nil {
+			return fmt.Errorf("release_functions[%d]: %w", i, err)
+		}
+	}
+
+	for i, fn := range c.RegistrationFunctions {
+		if err := validateFunctionName(fn); err != nil {
+			return fmt.Errorf("registration_functions[%d]: %w", i, err)
+		}
+	}
+
+	for i, fn := range c.NonOwningWrappers {
+		if err := validateFunctionName(fn); err != nil {
+			return fmt.Errorf("non_owning_wrappers[%d]: %w", i, err)
+		}
+	}
+
+	for i, fn := range c.OwningWrappers {
+		if err := validateFunctionName(fn); err != nil {
+			return fmt.Errorf("owning_wrappers[%d]: %w", i, err)
+		}
+	}
+
+	for i, writer := range c.Writers {
+		if _, _, err := splitQualifiedName(writer); err != nil {
+			return fmt.Errorf("writers[%d]: %w", i, err)
+		}
+	}
+
+	switch c.Collections {
+	case "", trustCollections, requireLoopCollections:
+	default:
+		return fmt.Errorf("collections: %q must be %q or %q", c.Collections, trustCollections, requireLoopCollections)
+	}
+
+	for i, pkg := range c.Exclude.Packages {
+		if pkg == "" || strings.Contains(strings.TrimSuffix(pkg, "/..."), "...") {
+			return fmt.Errorf("exclude.packages[%d]: %q is not an import path", i, pkg)
+		}
+	}
+
+	for i, pattern := range c.Exclude.Paths {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("exclude.paths[%d]: %q: %w", i, pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// splitQualifiedName splits a name like `example.com/pool.Conn` in its package path and name
+func splitQualifiedName(name string) (string, string, error) {
+	dot := strings.LastIndex(name, ".")
+	if dot <= strings.LastIndex(name, "/") || dot == len(name)-1 {
+		return "", "", fmt.Errorf("%q must be a qualified name like \"example.com/pool.Conn\"", name)
+	}
+
+	return name[:dot], name[dot+1:], nil
+}
+
+// validateFunctionName checks that the name is the full name of a function like `example.com/pool.Get`, or of a
+// method like `(*example.com/pool.Pool).Put` or `(example.com/pool.Pool).Put`
+func validateFunctionName(name string) error {
+	invalid := fmt.Errorf("%q must be a qualified name like \"example.com/pool.Get\" or \"(*example.com/pool.Pool).Put\"", name)
+	qualified := name
+
+	if strings.HasPrefix(name, "(") {
+		end := strings.Index(name, ").")
+		if end < 0 || !token.IsIdentifier(name[end+2:]) {
+			return invalid
+		}
+
+		qualified = strings.TrimPrefix(name[1:end], "*")
+	}
+
+	pkgPath, funcName, err := splitQualifiedName(qualified)
+	if err != nil || !token.IsIdentifier(funcName) || strings.ContainsAny(pkgPath, "()*") {
+		return invalid
+	}
+
+	return nil
+}
+
+// rules returns the rules for the resources of the configuration
+func (c *Config) rules() ruleSet {
+	rules := make(ruleSet, 0, len(c.Resources))
+
+	for _, resource := range c.Resources {
+		pkgPath, typeName, _ := splitQualifiedName(resource.Type)
+		action := "released"
+
+		if len(resource.Release) == 0 {
+			action = "called"
+		}
+
+		rules = append(rules, &resourceRule{
+			pkgPath:  pkgPath,
+			typeName: typeName,
+			methods:  resource.Release,
+			action:   action,
+		})
+	}
+
+	return rules
+}
+
+// isReleaseFunction checks whether the function with the given full name releases the resources it receives
+func (c *Config) isReleaseFunction(fullName string) bool {
+	for _, fn := range c.ReleaseFunctions {
+		if fn == fullName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRegistrationFunction checks whether the function with the given full name registers the resources it receives to
+// be released later
+func (c *Config) isRegistrationFunction(fullName string) bool {
+	for _, fn := range defaultRegistrationFunctions {
+		if fn == fullName {
+			return true
+		}
+	}
+
+	for _, fn := range c.RegistrationFunctions {
+		if fn == fullName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isNonOwningWrapper checks whether the closers returned by the function with the given full name don't have to be
+// released
+func (c *Config) isNonOwningWrapper(fullName string) bool {
+	for _, fn := range defaultNonOwningWrappers {
+		if fn == fullName {
+			return true
+		}
+	}
+
+	for _, fn := range c.NonOwningWrappers {
+		if fn == fullName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isOwningWrapper checks whether the function with the given full name wraps the closers it receives
+func (c *Config) isOwningWrapper(fullName string) bool {
+	for _, fn := range defaultOwningWrappers {
+		if fn == fullName {
+			return true
+		}
+	}
+
+	for _, fn := range c.OwningWrappers {
+		if fn == fullName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isWriter checks whether the type or the function with the given full name is a writer, either one of the default
+// ones or one of the configuration
+func (c *Config) isWriter(fullName string) bool {
+	for _, writer := range defaultWriters {
+		if writer == fullName {
+			return true
+		}
+	}
+
+	for _, writer := range c.Writers {
+		if writer == fullName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPackageExcluded checks whether the package with the given import path is excluded
+func (c *Config) isPackageExcluded(path string) bool {
+	for _, pkg := range c.Exclude.Packages {
+		if prefix := strings.TrimSuffix(pkg, "/..."); prefix != pkg {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+		} else if path == pkg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPathExcluded checks whether the file with the given path is excluded
+func (c *Config) isPathExcluded(path string) bool {
+	elems := strings.Split(filepath.ToSlash(path), "/")
+
+	for _, pattern := range c.Exclude.Paths {
+		for i := range elems {
+			if ok, _ := filepath.Match(pattern, strings.Join(elems[i:], "/")); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "closecheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		file    string
+		content string
+		err     string
+	}{
+		{"unknown.yaml", "resource:\n  - type: pool.Conn\n", "field resource not found"},
+		{"unknown.json", `{"resource": []}`, `unknown field "resource"`},
+		{"type.yaml", "resources:\n  - type: Conn\n", `resources[0]: type: "Conn" must be a qualified name`},
+		{"method.yaml", "resources:\n  - type: example.com/pool.Conn\n    release: [Release()]\n", `resources[0]: release: "Release()" is not a method name`},
+		{"function.json", `{"release_functions": ["example.com/pool"]}`, `release_functions[0]: "example.com/pool" must be a qualified name`},
+		{"method.json", `{"release_functions": ["((*example.com/pool.Pool).Put"]}`, `release_functions[0]: "((*example.com/pool.Pool).Put" must be a qualified name`},
+		{"receiver.yaml", "release_functions: [\"(*example.com/pool.Pool.Put\"]\n", `release_functions[0]: "(*example.com/pool.Pool.Put" must be a qualified name`},
+		{"registration.yaml", "registration_functions: [Add]\n", `registration_functions[0]: "Add" must be a qualified name`},
+		{"wrapper.yaml", "non_owning_wrappers: [NopCloser]\n", `non_owning_wrappers[0]: "NopCloser" must be a qualified name`},
+		{"owning.json", `{"owning_wrappers": ["bufio"]}`, `owning_wrappers[0]: "bufio" must be a qualified name`},
+		{"writer.yaml", "writers: [os]\n", `writers[0]: "os" must be a qualified name`},
+		{"collections.yaml", "collections: always\n", `collections: "always" must be "trust" or "require-loop"`},
+		{"package.yaml", "exclude:\n  packages: [example.com/.../pool]\n", `exclude.packages[0]: "example.com/.../pool" is not an import path`},
+		{"path.yaml", "exclude:\n  paths: [\"[\"]\n", `exclude.paths[0]: "[": syntax error in pattern`},
+		{"config.toml", "", `unsupported extension ".toml"`},
+	}
+
+	for _, c := range cases {
+		path := filepath.Join(dir, c.file)
+		if err := ioutil.WriteFile(path, []byte(c.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := LoadConfig(path)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error containing %q, got %v", c.file, c.err, err)
+		}
+	}
+}
+
+func TestValidateFunctionName(t *testing.T) {
+	for _, name := range []string{"example.com/pool.Get", "(*example.com/pool.Pool).Put", "(example.com/pool.Pool).Put", "os.Create"} {
+		if err := validateFunctionName(name); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"((*example.com/pool.Pool).Put", "(**example.com/pool.Pool).Put", "(*example.com/pool.Pool)Put", "example.com/pool.Pool).Put", "(*Pool).Put", "pool.(Get)"} {
+		if err := validateFunctionName(name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
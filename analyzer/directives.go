@@ -0,0 +1,181 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const (
+	ignoreDirective = "//closecheck:ignore"
+	nolintDirective = "//nolint:"
+)
+
+// directive is a comment that suppresses the diagnostics of a line or of a whole function, like
+// `//closecheck:ignore the caller closes it` or `//nolint:closecheck // the caller closes it`
+type directive struct {
+	pos    token.Pos
+	name   string
+	reason string
+	file   string
+	// from and to are the lines covered by the directive
+	from int
+	to   int
+	used bool
+}
+
+// directives are the directives of a package, they filter the diagnostics reported while checking it
+type directives struct {
+	pass *analysis.Pass
+	list []*directive
+}
+
+func findDirectives(pass *analysis.Pass) *directives {
+	ds := &directives{pass: pass}
+
+	for _, file := range pass.Files {
+		var codeEnds map[int]token.Pos
+
+		for _, group := range file.Comments {
+			for _, c := range group.List {
+				name, reason, ok := parseDirective(c.Text)
+				if !ok {
+					continue
+				}
+
+				if codeEnds == nil {
+					codeEnds = ds.codeEnds(file)
+				}
+
+				pos := pass.Fset.Position(c.Slash)
+				d := &directive{pos: c.Slash, name: name, reason: reason, file: pos.Filename, from: pos.Line, to: pos.Line}
+
+				// a directive on its own line is for the next one
+				if end, ok := codeEnds[pos.Line]; !ok || end > c.Slash {
+					d.from, d.to = pos.Line+1, pos.Line+1
+				}
+
+				ds.list = append(ds.list, d)
+			}
+		}
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Doc == nil {
+				continue
+			}
+
+			for _, d := range ds.list {
+				// the directive covers the annotations of the doc comment too
+				if d.pos >= fn.Doc.Pos() && d.pos < fn.Doc.End() {
+					d.from = pass.Fset.Position(fn.Doc.Pos()).Line
+					d.to = pass.Fset.Position(fn.End()).Line
+				}
+			}
+		}
+	}
+
+	return ds
+}
+
+// codeEnds returns the position where the first node that ends on each line of the file ends
+func (ds *directives) codeEnds(file *ast.File) map[int]token.Pos {
+	ends := map[int]token.Pos{}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n.(type) {
+		case nil, *ast.CommentGroup, *ast.Comment:
+			return false
+		}
+
+		line := ds.pass.Fset.Position(n.End()).Line
+		if end, ok := ends[line]; !ok || n.End() < end {
+			ends[line] = n.End()
+		}
+
+		return true
+	})
+
+	return ends
+}
+
+// parseDirective returns the name and the reason of the directive in the comment, if any
+func parseDirective(text string) (string, string, bool) {
+	if rest := strings.TrimPrefix(text, ignoreDirective); rest != text {
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			return "", "", false
+		}
+
+		return ignoreDirective, strings.TrimSpace(rest), true
+	}
+
+	if rest := strings.TrimPrefix(text, nolintDirective); rest != text {
+		linters := rest
+		reason := ""
+
+		if i := strings.IndexAny(rest, " \t"); i >= 0 {
+			linters = rest[:i]
+			reason = strings.TrimSpace(rest[i:])
+
+			if !strings.HasPrefix(reason, "//") {
+				// the reason of a nolint directive must be a comment
+				reason = ""
+			}
+
+			reason = strings.TrimSpace(strings.TrimPrefix(reason, "//"))
+		}
+
+		for _, linter := range strings.Split(linters, ",") {
+			if linter == "closecheck" {
+				return nolintDirective + "closecheck", reason, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// filter returns a copy of the pass that drops the diagnostics suppressed by a directive
+func (ds *directives) filter() *analysis.Pass {
+	if len(ds.list) == 0 {
+		return ds.pass
+	}
+
+	filtered := *ds.pass
+	filtered.Report = func(diag analysis.Diagnostic) {
+		if !ds.suppresses(ds.pass.Fset.Position(diag.Pos)) {
+			ds.pass.Report(diag)
+		}
+	}
+
+	return &filtered
+}
+
+func (ds *directives) suppresses(pos token.Position) bool {
+	suppressed := false
+
+	for _, d := range ds.list {
+		if d.file == pos.Filename && d.from <= pos.Line && pos.Line <= d.to {
+			d.used = true
+			suppressed = true
+		}
+	}
+
+	return suppressed
+}
+
+// reportProblems reports the directives without a reason and the ones that don't suppress anything, it's called
+// after checking the package
+func (ds *directives) reportProblems() {
+	for _, d := range ds.list {
+		if d.reason == "" {
+			ds.pass.Reportf(d.pos, "%s directive must explain why the diagnostics are ignored", d.name)
+		}
+
+		if !d.used {
+			ds.pass.Reportf(d.pos, "%s directive doesn't suppress any diagnostic", d.name)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/cfg"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// closeWalker walks every path that starts at the assignment of a closer looking for the places where it's closed
+// twice
+type closeWalker struct {
+	av        *AssignVisitor
+	idToClose *posToClose
+	visited   map[closeState]bool
+	reported  map[token.Pos]bool
+	ok        bool
+}
+
+// closeState is where the closer was closed when a block is reached
+type closeState struct {
+	block    *cfg.Block
+	closed   token.Pos
+	deferred token.Pos
+}
+
+// checkDoubleClose reports the calls to Close that are reached after the closer is already closed, or after a defer
+// that closes it, on the same path
+func (av *AssignVisitor) checkDoubleClose(idToClose *posToClose) bool {
+	if idToClose.obj == nil || !idToClose.rule.isReleaseMethod("Close") {
+		return true
+	}
+
+	cw := &closeWalker{
+		av:        av,
+		idToClose: idToClose,
+		visited:   map[closeState]bool{},
+		reported:  map[token.Pos]bool{},
+		ok:        true,
+	}
+
+	cw.walk(idToClose.block, idToClose.index+1, closeState{})
+
+	return cw.ok
+}
+
+func (cw *closeWalker) walk(block *cfg.Block, start int, state closeState) {
+	idToClose := cw.idToClose
+
+	for i := start; i < len(block.Nodes); i++ {
+		node := block.Nodes[i]
+
+		if block == idToClose.block && i == idToClose.index {
+			// a new closer is assigned on the next iteration of a loop
+			return
+		}
+
+		if deferStmt, ok := node.(*ast.DeferStmt); ok {
+			if cw.av.closes(idToClose, deferStmt.Call) {
+				state = cw.closeDeferred(deferStmt, state)
+			}
+		} else {
+			for _, call := range cw.av.closeCallsIn(idToClose, node) {
+				state = cw.closeExplicitly(call, isChecked(node), state)
+			}
+		}
+
+		if cw.av.assignsObject(idToClose, node) {
+			state.closed, state.deferred = token.NoPos, token.NoPos
+		}
+
+		if _, isReturn := node.(*ast.ReturnStmt); isReturn {
+			return
+		}
+	}
+
+	for _, succ := range block.Succs {
+		next := closeState{block: succ, closed: state.closed, deferred: state.deferred}
+		if cw.visited[next] {
+			continue
+		}
+
+		cw.visited[next] = true
+
+		cw.walk(succ, 0, next)
+	}
+}
+
+func (cw *closeWalker) closeExplicitly(call *ast.CallExpr, checked bool, state closeState) closeState {
+	idToClose := cw.idToClose
+	doubleClose := cw.av.settings.Checks.DoubleClose
+
+	switch {
+	case state.closed.IsValid():
+		cw.report(call.Pos(), "%s (%s) is %s twice, it's already %s at line %d", idToClose.name, idToClose.typeName,
+			idToClose.rule.action, idToClose.rule.action, cw.av.pass.Fset.Position(state.closed).Line)
+	case state.deferred.IsValid() && !(doubleClose.AllowCheckedClose && checked && cw.av.isWriter(idToClose)):
+		cw.report(call.Pos(), "%s (%s) is %s twice, it's also %s by the defer at line %d", idToClose.name,
+			idToClose.typeName, idToClose.rule.action, idToClose.rule.action, cw.av.pass.Fset.Position(state.deferred).Line)
+	}
+
+	state.closed = call.Pos()
+
+	return state
+}
+
+func (cw *closeWalker) closeDeferred(deferStmt *ast.DeferStmt, state closeState) closeState {
+	idToClose := cw.idToClose
+
+	switch {
+	case state.deferred.IsValid():
+		cw.report(deferStmt.Pos(), "%s (%s) is %s twice, it's also %s by the defer at line %d", idToClose.name,
+			idToClose.typeName, idToClose.rule.action, idToClose.rule.action, cw.av.pass.Fset.Position(state.deferred).Line)
+	case state.closed.IsValid():
+		cw.report(deferStmt.Pos(), "%s (%s) is %s twice, it's already %s at line %d", idToClose.name,
+			idToClose.typeName, idToClose.rule.action, idToClose.rule.action, cw.av.pass.Fset.Position(state.closed).Line)
+	}
+
+	state.deferred = deferStmt.Pos()
+
+	return state
+}
+
+func (cw *closeWalker) report(pos token.Pos, format string, args ...interface{}) {
+	cw.ok = false
+
+	if cw.reported[pos] {
+		return
+	}
+
+	cw.reported[pos] = true
+
+	cw.av.pass.Report(analysis.Diagnostic{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// closeCallsIn returns the calls of the node that close the closer, the function literals are skipped since they
+// can run later
+func (av *AssignVisitor) closeCallsIn(idToClose *posToClose, node ast.Node) []*ast.CallExpr {
+	calls := []*ast.CallExpr{}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch castedNode := n.(type) {
+		case *ast.FuncLit, *ast.GoStmt:
+			return false
+		case *ast.CallExpr:
+			if av.closes(idToClose, castedNode) {
+				calls = append(calls, castedNode)
+				return false
+			}
+		}
+
+		return true
+	})
+
+	return calls
+}
+
+// closes checks whether the call always closes the closer, either calling Close on it or passing it to a function
+// that always closes it
+func (av *AssignVisitor) closes(idToClose *posToClose, call *ast.CallExpr) bool {
+	if av.isCloseCall(idToClose, call) {
+		return true
+	}
+
+	fndecl, _ := typeutil.Callee(av.pass.TypesInfo, call).(*types.Func)
+	fn := &ioCloserFunc{}
+
+	if fndecl == nil || !av.pass.ImportObjectFact(fndecl, fn) {
+		return false
+	}
+
+	if recv := receiverExpr(av.pass.TypesInfo, call); recv != nil && av.isCloserExpr(idToClose, recv) {
+		return fn.recv == alwaysClosed
+	}
+
+	for i, arg := range call.Args {
+		param := argParam(av.pass.TypesInfo, call, fndecl, i)
+		if param != receiverParam && param < len(fn.params) && fn.params[param] == alwaysClosed &&
+			av.isCloserExpr(idToClose, arg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isChecked checks whether the error returned by the calls of the node is checked, that is, it isn't discarded by
+// an expression statement or an assignment to the blank identifier
+func isChecked(node ast.Node) bool {
+	switch castedNode := node.(type) {
+	case *ast.ExprStmt:
+		return false
+	case *ast.AssignStmt:
+		for _, lhs := range castedNode.Lhs {
+			if !isBlank(lhs) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,22 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// encodeFact encodes the exported representation of a fact, the facts keep unexported fields so they implement
+// gob.GobEncoder through it
+func encodeFact(data interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeFact decodes the exported representation of a fact encoded by encodeFact
+func decodeFact(b []byte, data interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(data)
+}
@@ -0,0 +1,54 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/gob"
+	"go/ast"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+func TestFactsGobRoundTrip(t *testing.T) {
+	facts := map[reflect.Type]analysis.Fact{
+		reflect.TypeOf(&ioCloserFunc{}): &ioCloserFunc{
+			paramNames:         []string{"c", "n"},
+			argsThatAreClosers: []bool{true, false},
+			recvName:           ast.NewIdent("w"),
+			isCloser:           true,
+			params:             []paramUsage{alwaysClosed, notClosed},
+			recv:               stored,
+			takesOwnership:     []bool{false, false},
+		},
+		reflect.TypeOf(&releasedFields{}):  &releasedFields{method: "Close", fields: []string{"conn", "body"}},
+		reflect.TypeOf(&resultOwnership{}): &resultOwnership{closers: []bool{true, false}, borrowed: []bool{true, false}},
+	}
+
+	for _, factType := range Analyzer.FactTypes {
+		fact, ok := facts[reflect.TypeOf(factType)]
+		if !ok {
+			t.Errorf("%T: no fact to encode", factType)
+			continue
+		}
+
+		// the facts are encoded as interfaces, like the drivers that analyze the packages separately do
+		gob.Register(fact)
+
+		buf := &bytes.Buffer{}
+		if err := gob.NewEncoder(buf).Encode(&fact); err != nil {
+			t.Errorf("%T: encoding: %v", fact, err)
+			continue
+		}
+
+		var decoded analysis.Fact
+		if err := gob.NewDecoder(buf).Decode(&decoded); err != nil {
+			t.Errorf("%T: decoding: %v", fact, err)
+			continue
+		}
+
+		if got, want := decoded.(interface{ String() string }).String(), fact.(interface{ String() string }).String(); got != want {
+			t.Errorf("%T: decoded %q, want %q", fact, got, want)
+		}
+	}
+}
@@ -5,45 +5,139 @@ import (
 	"go/ast"
 	"go/token"
 	"go/types"
-	"unicode"
+	"sort"
+	"strings"
 
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/cfg"
 	"golang.org/x/tools/go/types/typeutil"
 )
 
-var (
-	enableFunctionDebugger   = false
-	showCloserFunctionsFound = false
-)
-
 // FunctionVisitor is in charge of preprocessing packages to find functions that close io.Closers
 type FunctionVisitor struct {
 	pass            *analysis.Pass
+	settings        *settings
+	cfgs            *ctrlflow.CFGs
 	receivers       map[*types.Func]*ioCloserFunc
 	localGlobalVars map[token.Pos]bool
+	// rangeLoops are the range statements of the package by the expression they range over, which is the node of
+	// the loop in the control-flow graph
+	rangeLoops map[ast.Expr]*ast.RangeStmt
+	// diagnostics are the problems found in the annotations while the facts are computed, they are reported once the
+	// exclusions and the directives are known
+	diagnostics []analysis.Diagnostic
 }
 
 type ioCloserFunc struct {
 	obj                *types.Func
 	fdecl              *ast.FuncDecl
 	argsThatAreClosers []bool
-	argNames           []*ast.Ident
-	isCloser           bool
+	// argNames are the names of the parameters, nil for the unnamed ones
+	argNames []*ast.Ident
+	// paramNames are the names of the parameters in the signature, they are kept when the fact is imported
+	paramNames []string
+	recvName   *ast.Ident
+	isCloser   bool
+	// params is what the function does with the closer received in each parameter
+	params []paramUsage
+	// recv is what the function does with its receiver when the receiver is a closer
+	recv paramUsage
+	// takesOwnership are the parameters annotated with takes-ownership, the function releases them or keeps them
+	takesOwnership []bool
+	// returnsOwned is set when the function is annotated with returns-owned
+	returnsOwned bool
 }
 
 func (c *ioCloserFunc) AFact() {}
 
-// String is the string representation of the fact
+// ioCloserFuncData is how ioCloserFunc is encoded, the facts are encoded with gob when the packages are analyzed
+// separately, like with `go vet -vettool`
+type ioCloserFuncData struct {
+	ParamNames         []string
+	ArgsThatAreClosers []bool
+	RecvName           string
+	IsCloser           bool
+	Params             []paramUsage
+	Recv               paramUsage
+	TakesOwnership     []bool
+	ReturnsOwned       bool
+}
+
+// GobEncode encodes what the callers of the function need to know, the declaration isn't kept
+func (c *ioCloserFunc) GobEncode() ([]byte, error) {
+	data := ioCloserFuncData{
+		ParamNames:         c.paramNames,
+		ArgsThatAreClosers: c.argsThatAreClosers,
+		IsCloser:           c.isCloser,
+		Params:             c.params,
+		Recv:               c.recv,
+		TakesOwnership:     c.takesOwnership,
+		ReturnsOwned:       c.returnsOwned,
+	}
+
+	if c.recvName != nil {
+		data.RecvName = c.recvName.Name
+	}
+
+	return encodeFact(data)
+}
+
+// GobDecode decodes the fact encoded by GobEncode
+func (c *ioCloserFunc) GobDecode(b []byte) error {
+	data := ioCloserFuncData{}
+	if err := decodeFact(b, &data); err != nil {
+		return err
+	}
+
+	*c = ioCloserFunc{
+		paramNames:         data.ParamNames,
+		argsThatAreClosers: data.ArgsThatAreClosers,
+		isCloser:           data.IsCloser,
+		params:             data.Params,
+		recv:               data.Recv,
+		takesOwnership:     data.TakesOwnership,
+		returnsOwned:       data.ReturnsOwned,
+	}
+
+	if data.RecvName != "" {
+		c.recvName = ast.NewIdent(data.RecvName)
+	}
+
+	return nil
+}
+
+// String is the string representation of the fact, like "c always closed, r not closed"
 func (c *ioCloserFunc) String() string {
-	if c.isCloser {
-		return "is closer"
+	usages := []string{}
+
+	if c.recvName != nil {
+		usages = append(usages, fmt.Sprintf("receiver %s", c.recv))
+	}
+
+	for i, usage := range c.params {
+		name := ""
+		if i < len(c.paramNames) {
+			name = c.paramNames[i]
+		}
+
+		if i < len(c.takesOwnership) && c.takesOwnership[i] {
+			usages = append(usages, fmt.Sprintf("%s owned", name))
+		} else if i < len(c.argsThatAreClosers) && c.argsThatAreClosers[i] {
+			usages = append(usages, fmt.Sprintf("%s %s", name, usage))
+		}
 	}
 
-	return "is not closer"
+	if len(usages) == 0 {
+		return "no closers"
+	}
+
+	return strings.Join(usages, ", ")
 }
 
 func (pp *FunctionVisitor) debug(n ast.Node, template string, args ...interface{}) {
-	if !enableFunctionDebugger {
+	if !pp.settings.debug.Functions {
 		return
 	}
 
@@ -54,8 +148,10 @@ func (pp *FunctionVisitor) debug(n ast.Node, template string, args ...interface{
 
 // this function finds functions that receive and closes an io.Closer
 func (pp *FunctionVisitor) findFunctionsThatReceiveAnIOCloser() map[*types.Func]*ioCloserFunc {
+	pp.cfgs = pp.pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
 	pp.receivers = map[*types.Func]*ioCloserFunc{}
 	pp.localGlobalVars = map[token.Pos]bool{}
+	pp.rangeLoops = map[ast.Expr]*ast.RangeStmt{}
 
 	for _, file := range pp.pass.Files {
 		for _, decl := range file.Decls {
@@ -77,7 +173,7 @@ func (pp *FunctionVisitor) findFunctionsThatReceiveAnIOCloser() map[*types.Func]
 							continue
 						}
 
-						if isCloserReceiver(obj.Type().Underlying()) {
+						if pp.isCloserReceiver(obj.Type()) {
 							pp.localGlobalVars[name.NamePos] = true
 						}
 					}
@@ -87,6 +183,8 @@ func (pp *FunctionVisitor) findFunctionsThatReceiveAnIOCloser() map[*types.Func]
 					continue
 				}
 
+				pp.findRangeLoops(cDecl.Body)
+
 				fn, ok := pp.pass.TypesInfo.Defs[cDecl.Name].(*types.Func)
 				if !ok {
 					continue
@@ -103,85 +201,303 @@ func (pp *FunctionVisitor) findFunctionsThatReceiveAnIOCloser() map[*types.Func]
 
 				receivesCloser := false
 				argsThatAreClosers := make([]bool, params.Len())
+				paramNames := make([]string, params.Len())
 				argNames := []*ast.Ident{}
 
-				for _, params := range cDecl.Type.Params.List {
-					argNames = append(argNames, params.Names...) // FIXME: should only contain io.Closers
+				for _, field := range cDecl.Type.Params.List {
+					if len(field.Names) == 0 {
+						argNames = append(argNames, nil)
+					}
+
+					argNames = append(argNames, field.Names...)
 				}
 
 				for i := 0; i < params.Len(); i++ {
 					param := params.At(i)
+					paramNames[i] = param.Name()
 
-					if isCloserReceiver(param.Type()) {
+					if pp.isCloserReceiver(param.Type()) || pp.isCloserSlice(param.Type()) {
 						receivesCloser = true
 						argsThatAreClosers[i] = true
 					}
 				}
 
-				if receivesCloser {
+				ann := parseAnnotations(cDecl.Doc)
+				recvName := pp.closerReceiver(cDecl, fn)
+
+				if receivesCloser || recvName != nil || !ann.isEmpty() {
 					pp.receivers[fn] = &ioCloserFunc{
 						obj:                fn,
 						fdecl:              cDecl,
 						argsThatAreClosers: argsThatAreClosers,
 						argNames:           argNames,
+						paramNames:         paramNames,
+						recvName:           recvName,
+						params:             make([]paramUsage, params.Len()),
+						takesOwnership:     pp.paramsThatTakeOwnership(fn, ann),
+						returnsOwned:       ann.returnsOwned,
 					}
 				}
 			}
 		}
 	}
 
-	for _, rcv := range pp.receivers {
-		for _, id := range rcv.argNames {
-			if pp.traverse(id, rcv.fdecl.Body.List) {
-				rcv.isCloser = true
+	pp.inferParamUsages()
+
+	for _, rcv := range pp.sortedReceivers() {
+		if pp.settings.debug.CloserFunctions {
+			fmt.Println("found closer function:", rcv.obj.FullName(), "closer:", rcv.isCloser, "pos:", rcv.obj.Pos())
+		}
+
+		if rcv.recvName != nil && !rcv.releasesParam(receiverParam) && rcv.fdecl.Type.Params.NumFields() == 0 {
+			// methods of closers that don't release them aren't interesting for the callers
+			delete(pp.receivers, rcv.obj)
+			continue
+		}
+
+		pp.pass.ExportObjectFact(rcv.obj, rcv)
+	}
+
+	return pp.receivers
+}
+
+// findRangeLoops records the range statements of the body, so the loops are found from the nodes of the
+// control-flow graph
+func (pp *FunctionVisitor) findRangeLoops(body *ast.BlockStmt) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		if rangeStmt, ok := n.(*ast.RangeStmt); ok {
+			pp.rangeLoops[rangeStmt.X] = rangeStmt
+		}
+
+		return true
+	})
+}
+
+// inferParamUsages finds the usages of the parameters of every function until they don't change anymore. A function
+// is checked again when the usages of a function it calls change, so chains of helpers and recursive helpers are
+// resolved no matter the order in which they are found. Every closer starts as always closed and the usages only
+// shrink, so helpers that call each other recursively and close the closer on every path are found to always close
+// it.
+func (pp *FunctionVisitor) inferParamUsages() {
+	callers := pp.findCallers()
+	queue := pp.sortedReceivers()
+	queued := map[*ioCloserFunc]bool{}
+
+	for _, rcv := range queue {
+		queued[rcv] = true
+
+		for i := range rcv.params {
+			if rcv.argsThatAreClosers[i] {
+				rcv.params[i] = alwaysClosed
 			}
 		}
 
-		if showCloserFunctionsFound {
-			fmt.Println("found closer function:", rcv.obj.FullName(), "closer:", rcv.isCloser, "pos:", rcv.obj.Pos())
+		if rcv.recvName != nil {
+			rcv.recv = alwaysClosed
+		}
+	}
+
+	for len(queue) > 0 {
+		rcv := queue[0]
+		queue = queue[1:]
+		queued[rcv] = false
+
+		if !pp.findParamUsages(rcv) {
+			continue
+		}
+
+		for _, caller := range callers[rcv] {
+			if !queued[caller] {
+				queued[caller] = true
+				queue = append(queue, caller)
+			}
 		}
 	}
+}
+
+// findCallers finds the functions of the package that call each function, sorted by position
+func (pp *FunctionVisitor) findCallers() map[*ioCloserFunc][]*ioCloserFunc {
+	callers := map[*ioCloserFunc][]*ioCloserFunc{}
+
+	for _, caller := range pp.sortedReceivers() {
+		seen := map[*ioCloserFunc]bool{}
+
+		ast.Inspect(caller.fdecl.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			fn, ok := typeutil.Callee(pp.pass.TypesInfo, call).(*types.Func)
+			if !ok {
+				return true
+			}
+
+			if callee, ok := pp.receivers[fn]; ok && !seen[callee] {
+				seen[callee] = true
+				callers[callee] = append(callers[callee], caller)
+			}
+
+			return true
+		})
+	}
 
-	// TODO: optimize this, no need to loop again over all receivers
+	return callers
+}
+
+// sortedReceivers returns the functions that receive a closer sorted by position, so the results don't depend on
+// the order of the map
+func (pp *FunctionVisitor) sortedReceivers() []*ioCloserFunc {
+	receivers := make([]*ioCloserFunc, 0, len(pp.receivers))
 	for _, rcv := range pp.receivers {
-		for _, id := range rcv.argNames {
-			if pp.traverse(id, rcv.fdecl.Body.List) {
-				rcv.isCloser = true
+		receivers = append(receivers, rcv)
+	}
+
+	sort.Slice(receivers, func(i, j int) bool {
+		return receivers[i].obj.Pos() < receivers[j].obj.Pos()
+	})
+
+	return receivers
+}
+
+// reportDiagnostics reports the problems found in the annotations through the given pass, which drops the excluded
+// and ignored ones
+func (pp *FunctionVisitor) reportDiagnostics(pass *analysis.Pass) {
+	for _, diag := range pp.diagnostics {
+		pass.Report(diag)
+	}
+}
+
+// paramsThatTakeOwnership returns which parameters of the function are annotated with takes-ownership
+func (pp *FunctionVisitor) paramsThatTakeOwnership(fn *types.Func, ann annotations) []bool {
+	params := fn.Type().(*types.Signature).Params()
+	takesOwnership := make([]bool, params.Len())
+
+	for _, name := range ann.takesOwnership {
+		found := false
+
+		for i := 0; i < params.Len(); i++ {
+			if params.At(i).Name() == name {
+				takesOwnership[i] = true
+				found = true
 			}
 		}
 
-		if showCloserFunctionsFound {
-			fmt.Println("found closer function:", rcv.obj.FullName(), "closer:", rcv.isCloser, "pos:", rcv.obj.Pos())
+		if !found {
+			pp.diagnostics = append(pp.diagnostics, analysis.Diagnostic{
+				Pos:     ann.takesOwnershipPos,
+				Message: fmt.Sprintf("%s: %s is not a parameter of %s", takesOwnershipAnnotation, name, fn.Name()),
+			})
 		}
+	}
 
-		pp.pass.ExportObjectFact(rcv.obj, rcv)
+	return takesOwnership
+}
+
+// closerReceiver returns the name of the receiver of the method when it's a closer and the method isn't one of the
+// methods that release it
+func (pp *FunctionVisitor) closerReceiver(fdecl *ast.FuncDecl, fn *types.Func) *ast.Ident {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil || len(fdecl.Recv.List[0].Names) == 0 {
+		return nil
 	}
 
-	return pp.receivers
+	rule := pp.settings.rules.newReturnVar(recv.Type()).rule
+	if rule == nil || rule.isReleaseMethod(fn.Name()) {
+		return nil
+	}
+
+	return fdecl.Recv.List[0].Names[0]
 }
 
-func isCloserReceiver(t types.Type) bool {
-	if types.Implements(t, closerType) {
-		return true
+// findParamUsages finds what the function does with each closer it receives, following every path of its
+// control-flow graph. It returns whether the usages changed.
+func (pp *FunctionVisitor) findParamUsages(rcv *ioCloserFunc) bool {
+	g := pp.cfgs.FuncDecl(rcv.fdecl)
+	params := make([]paramUsage, len(rcv.params))
+	recv := notClosed
+
+	for i, id := range rcv.argNames {
+		if id != nil && id.Name != "_" && i < len(rcv.argsThatAreClosers) && rcv.argsThatAreClosers[i] {
+			params[i] = pp.paramUsage(g, id)
+		}
 	}
 
-	// special case: a struct containing a io.Closer fields that implements io.Closer, like http.Response.Body
-	ptr, ok := t.Underlying().(*types.Pointer)
-	if !ok {
-		return false
+	if rcv.recvName != nil {
+		recv = pp.paramUsage(g, rcv.recvName)
 	}
 
-	str, ok := ptr.Elem().Underlying().(*types.Struct)
-	if !ok {
+	changed := recv != rcv.recv
+
+	for i := range params {
+		changed = changed || params[i] != rcv.params[i]
+	}
+
+	rcv.params = params
+	rcv.recv = recv
+	rcv.isCloser = rcv.releasesParam(receiverParam)
+
+	for i := range params {
+		rcv.isCloser = rcv.isCloser || rcv.releasesParam(i)
+	}
+
+	return changed
+}
+
+func (pp *FunctionVisitor) paramUsage(g *cfg.CFG, id *ast.Ident) paramUsage {
+	found := map[paramUsage]bool{}
+
+	for _, block := range g.Blocks {
+		if !block.Live {
+			continue
+		}
+
+		for _, node := range block.Nodes {
+			found[pp.usageIn(id, node)] = true
+		}
+	}
+
+	leaks := pp.reachesReturn(id, g.Blocks[0], map[*cfg.Block]bool{})
+
+	// a parameter that is only released on some paths still has to be released by the callers on the other ones
+	switch {
+	case !leaks && !found[returned] && found[alwaysClosed] && !found[stored]:
+		return alwaysClosed
+	case !leaks && !found[returned] && found[stored]:
+		return stored
+	case found[alwaysClosed] || found[stored]:
+		return sometimesClosed
+	case found[returned]:
+		return returned
+	}
+
+	return notClosed
+}
+
+// reachesReturn checks whether there is a path from the block to a return where the parameter isn't closed, stored
+// or returned. The branches where the parameter is nil, like the one skipped by `if c != nil { c.Close() }`, have
+// nothing to release.
+func (pp *FunctionVisitor) reachesReturn(id *ast.Ident, block *cfg.Block, visited map[*cfg.Block]bool) bool {
+	if visited[block] {
 		return false
 	}
 
-	for i := 0; i < str.NumFields(); i++ {
-		v := str.Field(i)
-		fieldName := v.Name()
+	visited[block] = true
 
-		// TODO: don't ignore unexported fields if the struct is in the current package
-		if types.Implements(v.Type(), closerType) && unicode.IsUpper([]rune(fieldName)[0]) {
+	for _, node := range block.Nodes {
+		if pp.usageIn(id, node) != notClosed {
+			return false
+		}
+
+		if _, ok := node.(*ast.ReturnStmt); ok {
+			return true
+		}
+	}
+
+	skip := pp.nilBranch(id, block)
+
+	for i, succ := range block.Succs {
+		if i != skip && pp.reachesReturn(id, succ, visited) {
 			return true
 		}
 	}
@@ -189,111 +505,247 @@ func isCloserReceiver(t types.Type) bool {
 	return false
 }
 
-func (pp *FunctionVisitor) traverse(id *ast.Ident, stmts []ast.Stmt) bool {
-	for _, stmt := range stmts {
-		switch castedStmt := stmt.(type) {
-		case *ast.IfStmt:
-			pp.debug(castedStmt, "found if stmt")
+// nilBranch returns the index of the successor of a conditional block that is taken when the parameter is nil, or -1
+// if the condition doesn't compare the parameter with nil
+func (pp *FunctionVisitor) nilBranch(id *ast.Ident, block *cfg.Block) int {
+	x, _, nilBranch := nilComparison(pp.pass.TypesInfo, block)
+	if x == nil || !pp.isExprEqualToIdent(id, astutil.Unparen(x)) {
+		return -1
+	}
 
-			if pp.traverse(id, []ast.Stmt{castedStmt.Init}) {
-				return true
-			}
+	return nilBranch
+}
 
-			if pp.traverse(id, castedStmt.Body.List) {
-				return true
-			}
-		case *ast.ReturnStmt:
-			pp.debug(castedStmt, "found return stmt")
+// usageIn returns what the node of the control-flow graph does with the parameter
+func (pp *FunctionVisitor) usageIn(id *ast.Ident, node ast.Node) paramUsage {
+	switch castedNode := node.(type) {
+	case *ast.ReturnStmt:
+		pp.debug(castedNode, "found return stmt")
 
-			if pp.closesIdentOnAnyExpression(id, castedStmt.Results) {
-				return true
+		if pp.closesIdentOnAnyExpression(id, castedNode.Results) {
+			return alwaysClosed
+		}
+
+		for _, res := range castedNode.Results {
+			if pp.holds(id, res) {
+				return returned
 			}
+		}
+	case *ast.DeferStmt:
+		pp.debug(castedNode, "found defer stmt, checking id: %s", id.String())
 
-		case *ast.DeferStmt:
-			pp.debug(castedStmt, "found defer stmt, checking id: %s", id.String())
+		if pp.closesIdentOnExpression(id, castedNode.Call) {
+			return alwaysClosed
+		}
+	case *ast.GoStmt:
+		if pp.closesIdentOnExpression(id, castedNode.Call) {
+			return alwaysClosed
+		}
+	case *ast.ExprStmt:
+		pp.debug(castedNode, "found expr stmt")
 
-			if pp.closesIdentOnExpression(id, castedStmt.Call) {
-				return true
-			}
-		case *ast.ExprStmt:
-			pp.debug(castedStmt, "found expr stmt")
+		if pp.closesIdentOnExpression(id, castedNode.X) {
+			return alwaysClosed
+		}
+	case *ast.AssignStmt:
+		pp.debug(castedNode, "found assign stmt comparing against: %s", id.String())
 
-			if pp.closesIdentOnExpression(id, castedStmt.X) {
-				return true
+		if pp.closesIdentOnAnyExpression(id, castedNode.Rhs) {
+			return alwaysClosed
+		}
+
+		for i, rhs := range castedNode.Rhs {
+			if len(castedNode.Lhs) == len(castedNode.Rhs) && pp.isStorage(castedNode.Lhs[i]) && pp.holds(id, rhs) {
+				return stored
 			}
+		}
+	case *ast.SendStmt:
+		if pp.holds(id, castedNode.Value) {
+			return stored
+		}
+	case ast.Expr:
+		// conditions of if, for and switch statements
+		if pp.closesIdentOnExpression(id, castedNode) {
+			return alwaysClosed
+		}
+
+		if rangeStmt, ok := pp.rangeLoops[castedNode]; ok && pp.rangeReleases(id, rangeStmt) {
+			return alwaysClosed
+		}
+	}
+
+	return notClosed
+}
+
+// rangeReleases checks whether the loop ranges over the slice of closers and releases each of its elements, like
+// `for _, c := range cs { c.Close() }`
+func (pp *FunctionVisitor) rangeReleases(id *ast.Ident, rangeStmt *ast.RangeStmt) bool {
+	x, ok := astutil.Unparen(rangeStmt.X).(*ast.Ident)
+	if !ok || !pp.isIdentInPos(x, id.Pos()) || !pp.isCloserSlice(pp.pass.TypesInfo.TypeOf(x)) {
+		return false
+	}
+
+	elem, ok := rangeStmt.Value.(*ast.Ident)
+	if !ok || elem.Name == "_" {
+		return false
+	}
+
+	return pp.releasedIn(elem, rangeStmt.Body)
+}
+
+// releasedIn checks whether the body closes or stores the variable somewhere, like the body of a loop over closers
+func (pp *FunctionVisitor) releasedIn(id *ast.Ident, body ast.Node) bool {
+	released := false
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		if released || n == nil {
+			return false
+		}
+
+		if usage := pp.usageIn(id, n); usage == alwaysClosed || usage == stored {
+			released = true
+		}
+
+		return !released
+	})
+
+	return released
+}
+
+// isStorage checks whether a value assigned to the expression outlives the function, like a field or a global
+// variable
+func (pp *FunctionVisitor) isStorage(expr ast.Expr) bool {
+	switch castedExpr := astutil.Unparen(expr).(type) {
+	case *ast.SelectorExpr, *ast.IndexExpr, *ast.StarExpr:
+		return true
+	case *ast.Ident:
+		obj := pp.pass.TypesInfo.ObjectOf(castedExpr)
+
+		return obj != nil && obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope()
+	}
 
-		case *ast.AssignStmt:
-			pp.debug(castedStmt, "found assign stmt comparing against: %s", id.String())
+	return false
+}
 
-			if pp.closesIdentOnAnyExpression(id, castedStmt.Rhs) {
+// holds checks whether the value of the expression holds the parameter, like `c`, `&T{c}`, `append(cs, c)` or
+// `bufio.NewReader(c)`
+func (pp *FunctionVisitor) holds(id *ast.Ident, expr ast.Expr) bool {
+	switch castedExpr := astutil.Unparen(expr).(type) {
+	case *ast.Ident:
+		obj := pp.pass.TypesInfo.ObjectOf(castedExpr)
+
+		return obj != nil && obj.Pos() == id.Pos()
+	case *ast.UnaryExpr:
+		return castedExpr.Op == token.AND && pp.holds(id, castedExpr.X)
+	case *ast.CompositeLit:
+		for _, elt := range castedExpr.Elts {
+			if pp.holds(id, elt) {
 				return true
 			}
+		}
+	case *ast.KeyValueExpr:
+		return pp.holds(id, castedExpr.Value)
+	case *ast.CallExpr:
+		if tv, ok := pp.pass.TypesInfo.Types[castedExpr.Fun]; ok && tv.IsType() && len(castedExpr.Args) == 1 {
+			// conversion
+			return pp.holds(id, castedExpr.Args[0])
+		}
 
-		case *ast.BlockStmt:
-			pp.debug(castedStmt, "found block stmt")
+		if fn, ok := typeutil.Callee(pp.pass.TypesInfo, castedExpr).(*types.Func); ok && pp.settings.isOwningWrapper(fn.FullName()) {
+			// the wrapper owns the closer, like `bufio.NewReader(c)`
+			for _, arg := range castedExpr.Args {
+				if pp.holds(id, arg) {
+					return true
+				}
+			}
+		}
+
+		if fun, ok := astutil.Unparen(castedExpr.Fun).(*ast.Ident); ok {
+			if builtin, ok := pp.pass.TypesInfo.ObjectOf(fun).(*types.Builtin); ok && builtin.Name() == "append" {
+				for _, arg := range castedExpr.Args[1:] {
+					if pp.holds(id, arg) {
+						return true
+					}
+				}
+			}
 		}
 	}
 
 	return false
 }
 
-func (pp *FunctionVisitor) findKnownReceiverFromCall(call *ast.CallExpr) *ioCloserFunc {
-	fndecl, _ := typeutil.Callee(pp.pass.TypesInfo, call).(*types.Func)
-	if fndecl == nil {
-		return nil
-	}
+func (pp *FunctionVisitor) isCloserReceiver(t types.Type) bool {
+	return pp.settings.rules.newReturnVar(t).needsClosing
+}
 
-	fn := &ioCloserFunc{}
-	if !pp.pass.ImportObjectFact(fndecl, fn) {
-		return nil
-	}
+// isCloserSlice checks whether the type is a slice of closers, like the variadic parameter of
+// `closeAll(cs ...io.Closer)`
+func (pp *FunctionVisitor) isCloserSlice(t types.Type) bool {
+	slice, ok := t.Underlying().(*types.Slice)
 
-	return fn
+	return ok && pp.isCloserReceiver(slice.Elem())
 }
 
-func (pp *FunctionVisitor) getKnownCloser(call *ast.CallExpr) *ioCloserFunc {
-	if fn := pp.findKnownReceiverFromCall(call); fn != nil {
-		return fn
+// knownCloser returns the fact of the function, it's found in the receivers for the functions of the package
+func (pp *FunctionVisitor) knownCloser(fn *types.Func) *ioCloserFunc {
+	if rcv, ok := pp.receivers[fn]; ok {
+		return rcv
 	}
 
-	switch castedFun := call.Fun.(type) {
-	case *ast.Ident:
-		return pp.getKnownCloserFromIdent(castedFun)
-	case *ast.SelectorExpr:
-		return pp.getKnownCloserFromSelector(castedFun)
+	cl := &ioCloserFunc{}
+	if pp.pass.ImportObjectFact(fn, cl) {
+		return cl
 	}
 
 	return nil
 }
 
-func (pp *FunctionVisitor) getKnownCloserFromIdent(id *ast.Ident) *ioCloserFunc {
-	fndecl, ok := pp.pass.TypesInfo.ObjectOf(id).(*types.Func)
+// callReleases checks whether the call releases the closer passed to it, isCloser tells which expressions hold it
+func (pp *FunctionVisitor) callReleases(call *ast.CallExpr, isCloser func(ast.Expr) bool) bool {
+	fn, ok := typeutil.Callee(pp.pass.TypesInfo, call).(*types.Func)
 	if !ok {
-		return nil
+		return false
 	}
 
-	fn, ok := pp.receivers[fndecl]
-	if !ok {
-		return nil
+	if fn.FullName() == setFinalizer {
+		return len(call.Args) == 2 && isCloser(call.Args[0]) && pp.finalizerReleases(call.Args[1])
 	}
 
-	return fn
-}
+	if pp.settings.isReleaseFunction(fn.FullName()) || pp.settings.isRegistrationFunction(fn.FullName()) {
+		for _, arg := range call.Args {
+			if isCloser(arg) {
+				return true
+			}
+		}
+	}
 
-func (pp *FunctionVisitor) getKnownCloserFromSelector(sel *ast.SelectorExpr) *ioCloserFunc {
-	var knownCloser *ioCloserFunc
+	cl := pp.knownCloser(fn)
 
-	pp.visitSelectors(sel, func(id *ast.Ident) bool {
-		if fn := pp.getKnownCloserFromIdent(id); fn != nil {
-			knownCloser = fn
+	return cl != nil && cl.releasesArg(pp.pass.TypesInfo, call, fn, isCloser)
+}
 
+// finalizerReleases checks whether the finalizer given to runtime.SetFinalizer releases the object it receives
+func (pp *FunctionVisitor) finalizerReleases(finalizer ast.Expr) bool {
+	switch castedFinalizer := astutil.Unparen(finalizer).(type) {
+	case *ast.FuncLit:
+		params := castedFinalizer.Type.Params.List
+		if len(params) != 1 || len(params[0].Names) != 1 || params[0].Names[0].Name == "_" {
 			return false
 		}
 
-		return true
-	})
+		return pp.releasedIn(params[0].Names[0], castedFinalizer.Body)
+	case *ast.Ident:
+		fn, ok := pp.pass.TypesInfo.ObjectOf(castedFinalizer).(*types.Func)
+		if !ok {
+			return false
+		}
+
+		cl := pp.knownCloser(fn)
+
+		return cl != nil && cl.releasesParam(0)
+	}
 
-	return knownCloser
+	return false
 }
 
 func (pp *FunctionVisitor) closesIdentOnAnyExpression(id *ast.Ident, exprs []ast.Expr) bool {
@@ -313,12 +765,29 @@ func (pp *FunctionVisitor) closesIdentOnExpression(id *ast.Ident, expr ast.Expr)
 			return true
 		}
 
-		if cl := pp.getKnownCloser(castedExpr); cl != nil && cl.isCloser {
+		if pp.callReleases(castedExpr, func(expr ast.Expr) bool { return pp.isPosInExpression(id.Pos(), expr) }) {
 			return true
 		}
 
 	case *ast.SelectorExpr:
-		if pp.isPosInExpression(id.Pos(), castedExpr.X) && castedExpr.Sel.Name == "Close" {
+		if pp.isPosInExpression(id.Pos(), castedExpr.X) && pp.isReleaseMethod(id, castedExpr.Sel.Name) {
+			return true
+		}
+	case *ast.Ident:
+		// resources like context.CancelFunc are released by calling them
+		if rule := pp.settings.rules.newReturnVar(pp.pass.TypesInfo.ObjectOf(id).Type()).rule; rule != nil && len(rule.methods) == 0 &&
+			pp.isIdentInPos(castedExpr, id.Pos()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isReleaseMethod checks whether the method with the given name releases the resource held by the identifier
+func (pp *FunctionVisitor) isReleaseMethod(id *ast.Ident, name string) bool {
+	for _, method := range pp.settings.rules.releaseMethodsOf(pp.pass.TypesInfo.ObjectOf(id).Type()) {
+		if method == name {
 			return true
 		}
 	}
@@ -342,12 +811,25 @@ func (pp *FunctionVisitor) isPosInExpression(pos token.Pos, expr ast.Expr) bool
 			return true
 		})
 
+		if !wasFound {
+			// a field of the identifier, like `res.Body`
+			return pp.isPosInExpression(pos, innermostSelectorX(castedExpr))
+		}
+
 		return wasFound
 	}
 
 	return false
 }
 
+func innermostSelectorX(sel *ast.SelectorExpr) ast.Expr {
+	if inner, ok := sel.X.(*ast.SelectorExpr); ok {
+		return innermostSelectorX(inner)
+	}
+
+	return sel.X
+}
+
 func (pp *FunctionVisitor) visitSelectors(sel *ast.SelectorExpr, cb func(id *ast.Ident) bool) {
 	if !cb(sel.Sel) {
 		return
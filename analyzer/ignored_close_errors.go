@@ -0,0 +1,179 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/cfg"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// defaultWriters are the resources whose Close errors report failed writes, the files are writers only when they
+// are opened for writing
+var defaultWriters = []string{
+	"os.Create",
+	"os.CreateTemp",
+	"os.OpenFile",
+	"io/ioutil.TempFile",
+	"compress/gzip.Writer",
+	"archive/zip.Writer",
+}
+
+// checkIgnoredCloseErrors reports the writers that are closed by a defer, an expression statement or an assignment to
+// the blank identifier, since the error returned by Close is dropped and the data that failed to be written is lost
+func (av *AssignVisitor) checkIgnoredCloseErrors(g *cfg.CFG, idToClose *posToClose) bool {
+	if idToClose.obj == nil || !av.isWriter(idToClose) {
+		return true
+	}
+
+	ok := true
+	region := av.reachableBlocks(idToClose.block)
+
+	for _, block := range g.Blocks {
+		if !region[block] {
+			continue
+		}
+
+		for i, node := range block.Nodes {
+			if block == idToClose.block && i <= idToClose.index {
+				continue
+			}
+
+			var call *ast.CallExpr
+
+			switch castedNode := node.(type) {
+			case *ast.DeferStmt:
+				call = castedNode.Call
+			case *ast.ExprStmt:
+				call, _ = astutil.Unparen(castedNode.X).(*ast.CallExpr)
+			case *ast.AssignStmt:
+				// `_ = f.Close()`
+				if len(castedNode.Lhs) == 1 && len(castedNode.Rhs) == 1 && isBlank(castedNode.Lhs[0]) {
+					call, _ = astutil.Unparen(castedNode.Rhs[0]).(*ast.CallExpr)
+				}
+			}
+
+			if call == nil || !av.isCloseCall(idToClose, call) || av.reportedCloses[call.Pos()] {
+				continue
+			}
+
+			ok = false
+			av.reportedCloses[call.Pos()] = true
+
+			diag := analysis.Diagnostic{
+				Pos: call.Pos(),
+				Message: fmt.Sprintf("the error returned by %s is ignored, the data written to %s (%s) can be lost",
+					types.ExprString(call), idToClose.name, idToClose.typeName),
+			}
+
+			if deferStmt, isDefer := node.(*ast.DeferStmt); isDefer {
+				diag.SuggestedFixes = av.returnCloseError(deferStmt)
+
+				if diag.SuggestedFixes == nil {
+					diag.Message += ", no fix is suggested because the function has no named error result to return it"
+				}
+			}
+
+			av.pass.Report(diag)
+		}
+	}
+
+	return ok
+}
+
+// isWriter checks whether the closer is a writer, either because of its type or because of the function that
+// returned it
+func (av *AssignVisitor) isWriter(idToClose *posToClose) bool {
+	if idToClose.field != nil {
+		return false
+	}
+
+	t := idToClose.obj.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil &&
+		av.settings.isWriter(named.Obj().Pkg().Path()+"."+named.Obj().Name()) {
+		return true
+	}
+
+	assign, ok := idToClose.block.Nodes[idToClose.index].(*ast.AssignStmt)
+	if !ok || len(assign.Rhs) != 1 {
+		return false
+	}
+
+	call, ok := astutil.Unparen(assign.Rhs[0]).(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+
+	fn, ok := typeutil.Callee(av.pass.TypesInfo, call).(*types.Func)
+
+	return ok && av.settings.isWriter(fn.FullName())
+}
+
+// returnCloseError suggests replacing the defer with one that returns the error of Close through the named error
+// result of the function, unless another error is returned. Nothing is suggested when the error result isn't named.
+func (av *AssignVisitor) returnCloseError(deferStmt *ast.DeferStmt) []analysis.SuggestedFix {
+	errName := av.namedErrorResult(deferStmt)
+	if errName == "" {
+		return nil
+	}
+
+	tokFile := av.pass.Fset.File(deferStmt.Pos())
+	indent := strings.Repeat("\t", int(deferStmt.Pos()-tokFile.LineStart(tokFile.Line(deferStmt.Pos()))))
+	lines := []string{
+		"defer func() {",
+		fmt.Sprintf("\tif cerr := %s; %s == nil {", types.ExprString(deferStmt.Call), errName),
+		fmt.Sprintf("\t\t%s = cerr", errName),
+		"\t}",
+		"}()",
+	}
+
+	return []analysis.SuggestedFix{{
+		Message: fmt.Sprintf("Return the error of %s in %s", types.ExprString(deferStmt.Call), errName),
+		TextEdits: []analysis.TextEdit{
+			{Pos: deferStmt.Pos(), End: deferStmt.End(), NewText: []byte(strings.Join(lines, "\n"+indent))},
+		},
+	}}
+}
+
+// namedErrorResult returns the name of the last result of the function that encloses the node when it's a named
+// error, or an empty string otherwise
+func (av *AssignVisitor) namedErrorResult(node ast.Node) string {
+	var fnType *ast.FuncType
+
+	for _, enclosing := range av.findPath(node) {
+		if fn, ok := enclosing.(*ast.FuncDecl); ok {
+			fnType = fn.Type
+			break
+		}
+
+		if fn, ok := enclosing.(*ast.FuncLit); ok {
+			fnType = fn.Type
+			break
+		}
+	}
+
+	if fnType == nil || fnType.Results == nil || len(fnType.Results.List) == 0 {
+		return ""
+	}
+
+	last := fnType.Results.List[len(fnType.Results.List)-1]
+	errorType := types.Universe.Lookup("error").Type()
+	if len(last.Names) == 0 || !types.Identical(av.pass.TypesInfo.TypeOf(last.Type), errorType) {
+		return ""
+	}
+
+	name := last.Names[len(last.Names)-1]
+	if name.Name == "_" {
+		return ""
+	}
+
+	return name.Name
+}
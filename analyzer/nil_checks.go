@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/cfg"
+)
+
+// comparedWithNil returns the expression that is compared with nil in the node, and the comparison operator
+func comparedWithNil(info *types.Info, node ast.Node) (ast.Expr, token.Token) {
+	cond, ok := node.(*ast.BinaryExpr)
+	if !ok || (cond.Op != token.EQL && cond.Op != token.NEQ) {
+		return nil, token.ILLEGAL
+	}
+
+	switch {
+	case isNil(info, cond.Y):
+		return cond.X, cond.Op
+	case isNil(info, cond.X):
+		return cond.Y, cond.Op
+	}
+
+	return nil, token.ILLEGAL
+}
+
+func isNil(info *types.Info, expr ast.Expr) bool {
+	id, ok := astutil.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	_, ok = info.ObjectOf(id).(*types.Nil)
+
+	return ok
+}
+
+// nilComparison returns the expression that the condition of the block compares with nil, along with the indexes of
+// the successors taken when it isn't nil and when it's nil. The expression is nil if the block doesn't end with a
+// comparison with nil.
+func nilComparison(info *types.Info, block *cfg.Block) (x ast.Expr, notNilBranch, nilBranch int) {
+	if len(block.Succs) != 2 || len(block.Nodes) == 0 {
+		return nil, -1, -1
+	}
+
+	x, op := comparedWithNil(info, block.Nodes[len(block.Nodes)-1])
+	if x == nil {
+		return nil, -1, -1
+	}
+
+	// the first successor is the branch taken when the condition is true
+	if op == token.EQL {
+		return x, 1, 0
+	}
+
+	return x, 0, 1
+}
@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// ownedField is a field of a struct of the package that is assigned the closers returned by calls, like `s.conn` in
+// `s.conn, err = net.Dial(...)`. The struct owns those closers, so it must release them.
+type ownedField struct {
+	field    *types.Var
+	owner    *types.Named
+	typeName string
+}
+
+// recordOwnedFields records the fields that are assigned the closers returned by the calls in the node, either by an
+// assignment or by a composite literal
+func (av *AssignVisitor) recordOwnedFields(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch castedNode := n.(type) {
+		case *ast.FuncLit:
+			// checked along with the body of the function literal
+			return false
+		case *ast.AssignStmt:
+			if len(castedNode.Rhs) == 1 && len(castedNode.Lhs) > 1 {
+				call, ok := astutil.Unparen(castedNode.Rhs[0]).(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+
+				for i, rv := range av.returnsThatAreClosers(call) {
+					if i < len(castedNode.Lhs) && rv.needsClosing {
+						av.recordOwnedField(castedNode.Lhs[i], rv)
+					}
+				}
+
+				return true
+			}
+
+			for i, rhs := range castedNode.Rhs {
+				if call, ok := astutil.Unparen(rhs).(*ast.CallExpr); ok && i < len(castedNode.Lhs) {
+					if rv := av.returnsThatAreClosers(call)[0]; rv.needsClosing {
+						av.recordOwnedField(castedNode.Lhs[i], rv)
+					}
+				}
+			}
+		case *ast.CompositeLit:
+			for i, elt := range castedNode.Elts {
+				value := elt
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					value = kv.Value
+				}
+
+				call, ok := astutil.Unparen(value).(*ast.CallExpr)
+				if !ok {
+					continue
+				}
+
+				rv := av.returnsThatAreClosers(call)[0]
+				if field := av.compositeField(castedNode, i); field != nil && rv.needsClosing {
+					named, _ := wrapperStruct(av.pass.TypesInfo.TypeOf(castedNode))
+					av.addOwnedField(field, named, rv)
+				}
+			}
+		}
+
+		return true
+	})
+}
+
+// recordOwnedField records the field when the expression is a field of a struct, like `s.conn`
+func (av *AssignVisitor) recordOwnedField(lhs ast.Expr, rv returnVar) {
+	sel, ok := astutil.Unparen(lhs).(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+
+	selection, ok := av.pass.TypesInfo.Selections[sel]
+	if !ok || selection.Kind() != types.FieldVal {
+		return
+	}
+
+	field, _ := selection.Obj().(*types.Var)
+	named, _ := wrapperStruct(av.pass.TypesInfo.TypeOf(sel.X))
+	av.addOwnedField(field, named, rv)
+}
+
+func (av *AssignVisitor) addOwnedField(field *types.Var, owner *types.Named, rv returnVar) {
+	if field == nil || owner == nil || field.Pkg() != av.pass.Pkg || av.ownedFields[field] != nil {
+		return
+	}
+
+	av.ownedFields[field] = &ownedField{field: field, owner: owner, typeName: rv.typeName}
+}
+
+// checkOwnedFields reports, at their declarations, the fields that own closers but aren't released by a release
+// method of their struct
+func (av *AssignVisitor) checkOwnedFields() {
+	fields := make([]*ownedField, 0, len(av.ownedFields))
+	for _, owned := range av.ownedFields {
+		fields = append(fields, owned)
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].field.Pos() < fields[j].field.Pos() })
+
+	for _, owned := range fields {
+		owner := owned.owner.Obj().Name()
+
+		if av.settings.rules.find(types.NewPointer(owned.owner)) == nil {
+			av.pass.Reportf(owned.field.Pos(), "field %s.%s (%s) holds a closer but %s doesn't have a method that releases it",
+				owner, owned.field.Name(), owned.typeName, owner)
+
+			continue
+		}
+
+		fact := &releasedFields{}
+		if av.pass.ImportObjectFact(owned.owner.Obj(), fact) && !fact.releases(owned.field) {
+			av.pass.Reportf(owned.field.Pos(), "field %s.%s (%s) holds a closer but %s.%s doesn't release it",
+				owner, owned.field.Name(), owned.typeName, owner, fact.method)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// paramUsage is what a function does with the closer it receives in one of its parameters
+type paramUsage int
+
+const (
+	notClosed paramUsage = iota
+	// sometimesClosed is used when the closer is closed or stored on some paths but not in all of them
+	sometimesClosed
+	alwaysClosed
+	// stored is used when the closer is stored in a field, a global variable or a channel on every path
+	stored
+	// returned is used when the closer is returned to the caller
+	returned
+)
+
+func (u paramUsage) String() string {
+	switch u {
+	case sometimesClosed:
+		return "sometimes closed"
+	case alwaysClosed:
+		return "always closed"
+	case stored:
+		return "stored"
+	case returned:
+		return "returned"
+	}
+
+	return "not closed"
+}
+
+// releases checks whether the caller doesn't have to release the closer it passed anymore. A closer that is only
+// sometimes closed still has to be released by the caller on the paths where the function doesn't close it.
+func (u paramUsage) releases() bool {
+	return u == alwaysClosed || u == stored
+}
+
+// receiverParam is the index used for the receiver of a method by argParam
+const receiverParam = -1
+
+// argParam returns the index of the parameter of the function that receives the i-th argument of the call, or
+// receiverParam when the argument is the receiver of a method expression like `(*T).Close(t)`
+func argParam(info *types.Info, call *ast.CallExpr, fn *types.Func, i int) int {
+	sig := fn.Type().(*types.Signature)
+
+	if sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+		if selection, ok := info.Selections[sel]; ok && selection.Kind() == types.MethodExpr {
+			i--
+		}
+	}
+
+	if i < 0 {
+		return receiverParam
+	}
+
+	if sig.Variadic() && i >= sig.Params().Len()-1 && !call.Ellipsis.IsValid() {
+		return sig.Params().Len() - 1
+	}
+
+	return i
+}
+
+// receiverExpr returns the receiver of a call to a method, like `c` in `c.closeAll()`
+func receiverExpr(info *types.Info, call *ast.CallExpr) ast.Expr {
+	sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+
+	if selection, ok := info.Selections[sel]; ok && selection.Kind() == types.MethodVal {
+		return sel.X
+	}
+
+	return nil
+}
+
+// releasesParam checks whether the function releases the closer received in the given parameter
+func (c *ioCloserFunc) releasesParam(param int) bool {
+	if param == receiverParam {
+		// methods like Write release their receiver only sometimes, when they flush it, but the caller still has to
+		// release it
+		return c.recv == alwaysClosed || c.recv == stored
+	}
+
+	if param < len(c.takesOwnership) && c.takesOwnership[param] {
+		return true
+	}
+
+	return param < len(c.params) && c.params[param].releases()
+}
+
+// releasesArg checks whether the call to fn releases the closer passed in the arguments or as the receiver, isCloser
+// tells which expressions hold it
+func (c *ioCloserFunc) releasesArg(info *types.Info, call *ast.CallExpr, fn *types.Func, isCloser func(ast.Expr) bool) bool {
+	if recv := receiverExpr(info, call); recv != nil && isCloser(recv) && c.releasesParam(receiverParam) {
+		return true
+	}
+
+	for i, arg := range call.Args {
+		if isCloser(arg) && c.releasesParam(argParam(info, call, fn, i)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,442 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/cfg"
+)
+
+// pathWalker walks every path of the control-flow graph that starts at the assignment of a closer
+type pathWalker struct {
+	av        *AssignVisitor
+	idToClose *posToClose
+	visited   map[pathState]bool
+	reported  map[string]bool
+	leaks     []*ast.ReturnStmt
+	released  bool
+	ok        bool
+	// closure is set when the paths of a function literal that captures the closer are walked, nothing is reported
+	// and returning the closer from the closure doesn't release it
+	closure bool
+}
+
+// pathState is what is known about the closer when a block is reached
+type pathState struct {
+	block      *cfg.Block
+	errChecked bool
+	// errReassigned is set once the error returned along with the closer is assigned again, its checks don't guard
+	// the closer anymore
+	errReassigned bool
+	// owner is the variable of the value that owns the closer, like r after `r := bufio.NewReader(f)` or c after
+	// `c.f = f`
+	owner types.Object
+}
+
+// checkPaths walks every path that starts at the assignment of the closer and reports the returns that are reached
+// before the closer is closed or returned
+func (av *AssignVisitor) checkPaths(idToClose *posToClose) bool {
+	pw := &pathWalker{
+		av:        av,
+		idToClose: idToClose,
+		visited:   map[pathState]bool{},
+		reported:  map[string]bool{},
+		ok:        true,
+	}
+
+	pw.walk(idToClose.block, idToClose.index+1, pathState{errChecked: idToClose.errObj == nil})
+	pw.reportLeaks()
+
+	return pw.ok
+}
+
+// closureReleasesOnEveryPath checks whether the closer captured by the function literal is released on every path of
+// its body, like `defer func() { f.Close() }()`
+func (av *AssignVisitor) closureReleasesOnEveryPath(idToClose *posToClose, lit *ast.FuncLit) bool {
+	inner := *idToClose
+	inner.errObj, inner.block, inner.index = nil, nil, -1
+
+	pw := &pathWalker{
+		av:        av,
+		idToClose: &inner,
+		visited:   map[pathState]bool{},
+		reported:  map[string]bool{},
+		ok:        true,
+		closure:   true,
+	}
+
+	g := av.cfgs.FuncLit(lit)
+	if len(g.Blocks) == 0 {
+		return false
+	}
+
+	pw.walk(g.Blocks[0], 0, pathState{errChecked: true})
+
+	return pw.ok
+}
+
+// reportLeaks reports the returns that are reached without closing the closer. A fix that closes it with a defer is
+// suggested when it's not closed or returned on any path.
+func (pw *pathWalker) reportLeaks() {
+	idToClose := pw.idToClose
+
+	sort.Slice(pw.leaks, func(i, j int) bool {
+		return pw.leaks[i].Return < pw.leaks[j].Return
+	})
+
+	for i, ret := range pw.leaks {
+		diag := analysis.Diagnostic{
+			Pos: idToClose.parent.Pos(),
+			Message: fmt.Sprintf("%s (%s) was not %s on return at line %d",
+				idToClose.name, idToClose.typeName, idToClose.rule.action, pw.av.pass.Fset.Position(ret.Return).Line),
+		}
+
+		if i == 0 && !pw.released && !pw.av.isInLoop(idToClose.block) {
+			diag.SuggestedFixes = pw.av.insertDeferredClose(idToClose)
+		}
+
+		pw.av.pass.Report(diag)
+	}
+}
+
+func (pw *pathWalker) walk(block *cfg.Block, start int, state pathState) {
+	idToClose := pw.idToClose
+
+	for i := start; i < len(block.Nodes); i++ {
+		node := block.Nodes[i]
+
+		if block == idToClose.block && i == idToClose.index {
+			// the assignment is reached again on the next iteration of a loop
+			pw.report("overwritten", analysis.Diagnostic{
+				Pos: idToClose.parent.Pos(),
+				Message: fmt.Sprintf("%s (%s) is overwritten on the next iteration of the loop without being %s",
+					idToClose.name, idToClose.typeName, idToClose.rule.action),
+			})
+
+			return
+		}
+
+		if pw.releases(node, state) {
+			pw.released = true
+
+			if deferStmt, isDefer := node.(*ast.DeferStmt); isDefer && !pw.closure {
+				pw.checkDefer(block, deferStmt, state)
+			}
+
+			return
+		}
+
+		if owner := pw.av.ownerAssigned(idToClose, node); owner != nil {
+			state.owner = owner
+		}
+
+		if idToClose.errObj != nil && pw.av.assignsObject(&posToClose{obj: idToClose.errObj}, node) {
+			state.errReassigned = true
+		}
+
+		if ret, isReturn := node.(*ast.ReturnStmt); isReturn {
+			pw.ok = false
+
+			if !pw.reported[fmt.Sprintf("return:%d", ret.Return)] {
+				pw.reported[fmt.Sprintf("return:%d", ret.Return)] = true
+				pw.leaks = append(pw.leaks, ret)
+			}
+
+			return
+		}
+	}
+
+	guard := idToClose
+	if state.errReassigned {
+		guard = &posToClose{obj: idToClose.obj, field: idToClose.field}
+	}
+
+	skip := pw.av.guardedSuccessor(guard, block)
+	errChecked := state.errChecked || skip != -1 && pw.av.isErrorCheck(guard, block.Nodes[len(block.Nodes)-1])
+
+	for i, succ := range block.Succs {
+		next := pathState{block: succ, errChecked: errChecked, errReassigned: state.errReassigned, owner: state.owner}
+		if i == skip || pw.visited[next] {
+			continue
+		}
+
+		pw.visited[next] = true
+
+		pw.walk(succ, 0, next)
+	}
+}
+
+// releases checks whether the node releases the closer, either directly or through the value that owns it
+func (pw *pathWalker) releases(node ast.Node, state pathState) bool {
+	if ret, isReturn := node.(*ast.ReturnStmt); isReturn && pw.closure {
+		// the closer is returned to the caller of the closure, it's only released when it's closed by the return
+		for _, res := range ret.Results {
+			if pw.av.returnsOrClosesIDOnExpression(pw.idToClose, res) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if pw.av.returnsOrClosesID(pw.idToClose, node) {
+		return true
+	}
+
+	return state.owner != nil && pw.av.returnsOrClosesID(ownerToClose(pw.idToClose, state.owner), node)
+}
+
+func (pw *pathWalker) checkDefer(block *cfg.Block, deferStmt *ast.DeferStmt, state pathState) {
+	idToClose := pw.idToClose
+
+	if pw.av.isInLoop(block) {
+		pw.report("defer-in-loop", analysis.Diagnostic{
+			Pos: deferStmt.Pos(),
+			Message: fmt.Sprintf("%s (%s) is %s by a defer inside a loop, it won't be %s until the function returns",
+				idToClose.name, idToClose.typeName, idToClose.rule.action, idToClose.rule.action),
+		})
+	}
+
+	if !state.errChecked {
+		pw.report("defer-before-check", analysis.Diagnostic{
+			Pos: deferStmt.Pos(),
+			Message: fmt.Sprintf("%s (%s) is %s by a defer before %s is checked",
+				idToClose.name, idToClose.typeName, idToClose.rule.action, idToClose.errObj.Name()),
+			SuggestedFixes: pw.av.moveDeferAfterErrorCheck(idToClose, deferStmt),
+		})
+	}
+}
+
+// report reports the diagnostic only once for each kind of problem found in the paths of the closer
+func (pw *pathWalker) report(kind string, diag analysis.Diagnostic) {
+	pw.ok = false
+
+	if pw.reported[kind] || pw.closure {
+		return
+	}
+
+	pw.reported[kind] = true
+
+	pw.av.pass.Report(diag)
+}
+
+// isInLoop checks whether the block can be reached again after it's executed
+func (av *AssignVisitor) isInLoop(block *cfg.Block) bool {
+	visited := map[*cfg.Block]bool{}
+	queue := append([]*cfg.Block{}, block.Succs...)
+
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		if next == block {
+			return true
+		}
+
+		if visited[next] {
+			continue
+		}
+
+		visited[next] = true
+		queue = append(queue, next.Succs...)
+	}
+
+	return false
+}
+
+// guardedSuccessor returns the index of the successor of a conditional block where the closer can't be open, that is,
+// the branch where the error returned along with it is not nil or the branch where the closer itself is nil.
+// It returns -1 if the condition doesn't guard the closer.
+func (av *AssignVisitor) guardedSuccessor(idToClose *posToClose, block *cfg.Block) int {
+	x, notNilBranch, nilBranch := nilComparison(av.pass.TypesInfo, block)
+	if x == nil {
+		return -1
+	}
+
+	if idToClose.errObj != nil && av.isObject(x, idToClose.errObj) {
+		return notNilBranch
+	}
+
+	if av.isPosInExpression(idToClose, x) {
+		return nilBranch
+	}
+
+	return -1
+}
+
+// isErrorCheck checks whether the node compares the error returned along with the closer with nil
+func (av *AssignVisitor) isErrorCheck(idToClose *posToClose, node ast.Node) bool {
+	x, _ := comparedWithNil(av.pass.TypesInfo, node)
+
+	return x != nil && idToClose.errObj != nil && av.isObject(x, idToClose.errObj)
+}
+
+// moveDeferAfterErrorCheck suggests moving the defer statement after the `if err != nil` block that follows it
+func (av *AssignVisitor) moveDeferAfterErrorCheck(idToClose *posToClose, deferStmt *ast.DeferStmt) []analysis.SuggestedFix {
+	ifStmt := av.findErrorCheckAfter(idToClose, deferStmt)
+	if ifStmt == nil {
+		return nil
+	}
+
+	tokFile := av.pass.Fset.File(deferStmt.Pos())
+	lineStart := tokFile.LineStart(tokFile.Line(deferStmt.Pos()))
+	lineEnd := deferStmt.End()
+
+	if line := tokFile.Line(deferStmt.End()); line < tokFile.LineCount() {
+		lineEnd = tokFile.LineStart(line + 1)
+	}
+
+	var buf bytes.Buffer
+
+	node := &printer.CommentedNode{Node: deferStmt, Comments: av.commentsBetween(deferStmt.Pos(), deferStmt.End())}
+	if err := format.Node(&buf, av.pass.Fset, node); err != nil {
+		return nil
+	}
+
+	// the comments at the end of the line are moved along with the statement
+	for _, group := range av.commentsBetween(deferStmt.End(), lineEnd) {
+		for _, comment := range group.List {
+			buf.WriteString(" " + comment.Text)
+		}
+	}
+
+	indent := strings.Repeat("\t", int(deferStmt.Pos()-lineStart))
+
+	return []analysis.SuggestedFix{{
+		Message: fmt.Sprintf("Move the defer after checking %s", idToClose.errObj.Name()),
+		TextEdits: []analysis.TextEdit{
+			{Pos: lineStart, End: lineEnd},
+			{Pos: ifStmt.End(), End: ifStmt.End(), NewText: []byte("\n" + indent + strings.ReplaceAll(buf.String(), "\n", "\n"+indent))},
+		},
+	}}
+}
+
+// insertDeferredClose suggests releasing the closer with a defer right after it's assigned, or after the
+// `if err != nil` block that follows the assignment
+func (av *AssignVisitor) insertDeferredClose(idToClose *posToClose) []analysis.SuggestedFix {
+	if idToClose.obj == nil {
+		return nil
+	}
+
+	assign, ok := idToClose.block.Nodes[idToClose.index].(*ast.AssignStmt)
+	if !ok {
+		return nil
+	}
+
+	stmts := av.enclosingStmtList(assign)
+	pos := token.NoPos
+
+	for _, stmt := range stmts {
+		if stmt == assign {
+			pos = assign.End()
+		}
+	}
+
+	if pos == token.NoPos {
+		// the closer is assigned in the init statement of an if, for or switch statement
+		return nil
+	}
+
+	if ifStmt := av.findErrorCheckAfter(idToClose, assign); ifStmt != nil {
+		pos = ifStmt.End()
+	}
+
+	tokFile := av.pass.Fset.File(assign.Pos())
+	indent := strings.Repeat("\t", int(assign.Pos()-tokFile.LineStart(tokFile.Line(assign.Pos()))))
+	text := "defer " + idToClose.rule.releaseCall(idToClose.name)
+
+	// the defer is added at the end of the line, after any comment
+	if line := tokFile.Line(pos); line < tokFile.LineCount() {
+		pos = tokFile.LineStart(line+1) - 1
+	}
+
+	return []analysis.SuggestedFix{{
+		Message: fmt.Sprintf("Add %s", text),
+		TextEdits: []analysis.TextEdit{
+			{Pos: pos, End: pos, NewText: []byte("\n" + indent + text)},
+		},
+	}}
+}
+
+// findErrorCheckAfter finds the `if err != nil` statement that follows the statement in the same block
+func (av *AssignVisitor) findErrorCheckAfter(idToClose *posToClose, stmt ast.Stmt) *ast.IfStmt {
+	stmts := av.enclosingStmtList(stmt)
+
+	for i, s := range stmts {
+		if s != stmt {
+			continue
+		}
+
+		for _, next := range stmts[i+1:] {
+			ifStmt, ok := next.(*ast.IfStmt)
+			if !ok || ifStmt.Init != nil {
+				continue
+			}
+
+			if _, op := comparedWithNil(av.pass.TypesInfo, ifStmt.Cond); op == token.NEQ && av.isErrorCheck(idToClose, ifStmt.Cond) {
+				return ifStmt
+			}
+		}
+	}
+
+	return nil
+}
+
+// enclosingStmtList returns the list of statements of the innermost block that contains the statement
+func (av *AssignVisitor) enclosingStmtList(stmt ast.Stmt) []ast.Stmt {
+	for _, node := range av.findPath(stmt) {
+		switch castedNode := node.(type) {
+		case *ast.BlockStmt:
+			return castedNode.List
+		case *ast.CaseClause:
+			return castedNode.Body
+		case *ast.CommClause:
+			return castedNode.Body
+		}
+	}
+
+	return nil
+}
+
+// commentsBetween returns the comments of the file that are found between the given positions
+func (av *AssignVisitor) commentsBetween(pos token.Pos, end token.Pos) []*ast.CommentGroup {
+	comments := []*ast.CommentGroup{}
+
+	for _, file := range av.pass.Files {
+		if pos < file.Pos() || end > file.End()+1 {
+			continue
+		}
+
+		for _, group := range file.Comments {
+			if group.Pos() >= pos && group.End() <= end {
+				comments = append(comments, group)
+			}
+		}
+	}
+
+	return comments
+}
+
+// findPath returns the nodes that enclose the given one, starting with the innermost
+func (av *AssignVisitor) findPath(node ast.Node) []ast.Node {
+	for _, file := range av.pass.Files {
+		if node.Pos() < file.Pos() || node.End() > file.End() {
+			continue
+		}
+
+		path, _ := astutil.PathEnclosingInterval(file, node.Pos(), node.End())
+
+		return path
+	}
+
+	return nil
+}
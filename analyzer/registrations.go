@@ -0,0 +1,76 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// setFinalizer registers the finalizer it receives, the object is only released when the finalizer releases it
+const setFinalizer = "runtime.SetFinalizer"
+
+// defaultRegistrationFunctions are the functions that register the resources they receive to be released later,
+// like `t.Cleanup(func() { f.Close() })`
+var defaultRegistrationFunctions = []string{
+	"(*testing.common).Cleanup",
+	"(testing.TB).Cleanup",
+	setFinalizer,
+}
+
+// registersRelease checks whether the arguments of a call to a registration function release the closer later,
+// either because the closer is passed, like `closers.Add(f)`, or because its release method or a closure that
+// releases it is passed, like `t.Cleanup(f.Close)`
+func (av *AssignVisitor) registersRelease(idToClose *posToClose, fn *types.Func, args []ast.Expr) bool {
+	if fn.FullName() == setFinalizer {
+		return av.finalizerReleases(idToClose, args)
+	}
+
+	for _, arg := range args {
+		if av.isPosInExpression(idToClose, arg) {
+			return true
+		}
+
+		switch castedArg := astutil.Unparen(arg).(type) {
+		case *ast.SelectorExpr:
+			if idToClose.rule.isReleaseMethod(castedArg.Sel.Name) && av.isCloserExpr(idToClose, castedArg.X) {
+				return true
+			}
+		case *ast.FuncLit:
+			if av.closureReleases(idToClose, castedArg) {
+				return true
+			}
+		case *ast.Ident:
+			if lit := av.closureOf(castedArg); lit != nil && av.closureReleases(idToClose, lit) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// finalizerReleases checks whether a call to runtime.SetFinalizer sets a finalizer that releases the closer, like
+// `runtime.SetFinalizer(f, func(f *os.File) { f.Close() })`. Removing the finalizer with nil, or setting one that
+// doesn't release its parameter, doesn't release the closer.
+func (av *AssignVisitor) finalizerReleases(idToClose *posToClose, args []ast.Expr) bool {
+	if len(args) != 2 || !av.isCloserExpr(idToClose, args[0]) {
+		return false
+	}
+
+	switch finalizer := astutil.Unparen(args[1]).(type) {
+	case *ast.FuncLit:
+		return av.closureCallReleases(idToClose, finalizer, args[:1])
+	case *ast.Ident:
+		if lit := av.closureOf(finalizer); lit != nil {
+			return av.closureCallReleases(idToClose, lit, args[:1])
+		}
+
+		fn, ok := av.pass.TypesInfo.ObjectOf(finalizer).(*types.Func)
+		cl := &ioCloserFunc{}
+
+		return ok && av.pass.ImportObjectFact(fn, cl) && cl.releasesParam(0)
+	}
+
+	return false
+}
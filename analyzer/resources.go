@@ -0,0 +1,165 @@
+package analyzer
+
+import (
+	"go/types"
+	"unicode"
+)
+
+// resourceRule describes a type that has to be released and the methods that release it
+type resourceRule struct {
+	// pkgPath and typeName identify the named type of the resource, pointers to it are resources too
+	pkgPath  string
+	typeName string
+	// iface is used instead of pkgPath and typeName to make any type that implements it a resource
+	iface *types.Interface
+	// methods are the methods that release the resource, calling the value itself releases it when it's empty
+	methods []string
+	// action describes the release in the diagnostics, like "closed" or "stopped"
+	action string
+}
+
+// ruleSet are the rules used to find the resources, the first one that matches a type is used
+type ruleSet []*resourceRule
+
+func defaultResourceRules() ruleSet {
+	return ruleSet{
+		// *sql.Rows, *os.File and http.Response.Body are all io.Closers
+		{iface: closerType, methods: []string{"Close"}, action: "closed"},
+		{pkgPath: "time", typeName: "Ticker", methods: []string{"Stop"}, action: "stopped"},
+		{pkgPath: "context", typeName: "CancelFunc", action: "called"},
+		{pkgPath: "database/sql", typeName: "Tx", methods: []string{"Rollback", "Commit"}, action: "committed or rolled back"},
+		{pkgPath: "os/exec", typeName: "Cmd", methods: []string{"Wait", "Run", "Output", "CombinedOutput"}, action: "waited for"},
+		{pkgPath: "bufio", typeName: "Writer", methods: []string{"Flush"}, action: "flushed"},
+	}
+}
+
+func (r *resourceRule) matches(t types.Type) bool {
+	if r.iface != nil {
+		return types.Implements(t, r.iface)
+	}
+
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+
+	obj := named.Obj()
+
+	return obj.Pkg() != nil && obj.Pkg().Path() == r.pkgPath && obj.Name() == r.typeName
+}
+
+// isReleaseMethod checks whether calling the method with the given name releases the resource
+func (r *resourceRule) isReleaseMethod(name string) bool {
+	for _, method := range r.methods {
+		if method == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// releaseCall returns the call that releases the resource with the given name, like `f.Close()`
+func (r *resourceRule) releaseCall(name string) string {
+	if len(r.methods) == 0 {
+		return name + "()"
+	}
+
+	return name + "." + r.methods[0] + "()"
+}
+
+func (rules ruleSet) find(t types.Type) *resourceRule {
+	for _, rule := range rules {
+		if rule.matches(t) {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+type field struct {
+	name     string
+	typeName string
+	obj      *types.Var
+	index    int
+	rule     *resourceRule
+}
+
+type returnVar struct {
+	needsClosing bool
+	typeName     string
+	fields       []field
+	rule         *resourceRule
+}
+
+func (rules ruleSet) newReturnVar(t types.Type) returnVar {
+	if rule := rules.find(t); rule != nil {
+		return returnVar{
+			needsClosing: true,
+			typeName:     t.String(),
+			fields:       []field{},
+			rule:         rule,
+		}
+	}
+
+	// special case: a struct containing a io.Closer fields that implements io.Closer, like http.Response.Body
+	ptr, ok := t.Underlying().(*types.Pointer)
+	if !ok {
+		return returnVar{
+			needsClosing: false,
+			fields:       []field{},
+		}
+	}
+
+	str, ok := ptr.Elem().Underlying().(*types.Struct)
+	if !ok {
+		return returnVar{
+			needsClosing: false,
+			fields:       []field{},
+		}
+	}
+
+	fields := []field{}
+
+	for i := 0; i < str.NumFields(); i++ {
+		v := str.Field(i)
+		fieldName := v.Name()
+
+		// TODO: don't ignore unexported fields if the struct is in the current package
+		if rule := rules.find(v.Type()); rule != nil && unicode.IsUpper([]rune(fieldName)[0]) {
+			fields = append(fields, field{
+				name:     fieldName,
+				typeName: v.Type().String(),
+				obj:      v,
+				index:    i,
+				rule:     rule,
+			})
+		}
+	}
+
+	return returnVar{
+		needsClosing: len(fields) > 0,
+		typeName:     t.String(),
+		fields:       fields,
+	}
+}
+
+// releaseMethodsOf returns the methods that release the resources held by a value of the given type
+func (rules ruleSet) releaseMethodsOf(t types.Type) []string {
+	rv := rules.newReturnVar(t)
+	if rv.rule != nil {
+		return rv.rule.methods
+	}
+
+	methods := []string{}
+	for _, field := range rv.fields {
+		methods = append(methods, field.rule.methods...)
+	}
+
+	return methods
+}
@@ -0,0 +1,235 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// resultOwnership is the fact exported for the functions that return closers, it tells whether each result is a new
+// closer owned by the caller or a borrowed one, like the connection shared by a client returned from a getter, that
+// the caller doesn't have to release
+type resultOwnership struct {
+	// closers are the results that are closers
+	closers []bool
+	// borrowed are the results that the callers don't have to release
+	borrowed []bool
+}
+
+func (r *resultOwnership) AFact() {}
+
+// resultOwnershipData is how resultOwnership is encoded
+type resultOwnershipData struct {
+	Closers  []bool
+	Borrowed []bool
+}
+
+func (r *resultOwnership) GobEncode() ([]byte, error) {
+	return encodeFact(resultOwnershipData{Closers: r.closers, Borrowed: r.borrowed})
+}
+
+func (r *resultOwnership) GobDecode(b []byte) error {
+	data := resultOwnershipData{}
+	if err := decodeFact(b, &data); err != nil {
+		return err
+	}
+
+	r.closers, r.borrowed = data.Closers, data.Borrowed
+
+	return nil
+}
+
+// String is the string representation of the fact, like "result 0 borrowed"
+func (r *resultOwnership) String() string {
+	results := []string{}
+
+	for i, closer := range r.closers {
+		if !closer {
+			continue
+		}
+
+		if r.borrowed[i] {
+			results = append(results, fmt.Sprintf("result %d borrowed", i))
+		} else {
+			results = append(results, fmt.Sprintf("result %d owned", i))
+		}
+	}
+
+	return strings.Join(results, ", ")
+}
+
+func (r *resultOwnership) isBorrowed(i int) bool {
+	return i < len(r.borrowed) && r.borrowed[i]
+}
+
+func (r *resultOwnership) hasBorrowed() bool {
+	for _, borrowed := range r.borrowed {
+		if borrowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findFunctionsThatReturnBorrowedClosers finds the functions that return closers owned by someone else, like fields
+// of their receivers or global variables. The functions are checked until nothing changes, so functions that return
+// the results of other getters are found too.
+func (pp *FunctionVisitor) findFunctionsThatReturnBorrowedClosers() {
+	facts := map[*types.Func]*resultOwnership{}
+	inferred := []*ast.FuncDecl{}
+
+	for _, file := range pp.pass.Files {
+		for _, decl := range file.Decls {
+			fdecl, ok := decl.(*ast.FuncDecl)
+			if !ok || fdecl.Body == nil {
+				continue
+			}
+
+			fn, ok := pp.pass.TypesInfo.Defs[fdecl.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+
+			results := fn.Type().(*types.Signature).Results()
+			fact := &resultOwnership{closers: make([]bool, results.Len()), borrowed: make([]bool, results.Len())}
+			returnsCloser := false
+
+			for i := 0; i < results.Len(); i++ {
+				fact.closers[i] = pp.settings.rules.newReturnVar(results.At(i).Type()).needsClosing
+				returnsCloser = returnsCloser || fact.closers[i]
+			}
+
+			if !returnsCloser {
+				continue
+			}
+
+			facts[fn] = fact
+
+			ann := parseAnnotations(fdecl.Doc)
+			if ann.returnsOwned || ann.returnsBorrowed {
+				for i := range fact.borrowed {
+					fact.borrowed[i] = fact.closers[i] && ann.returnsBorrowed
+				}
+
+				continue
+			}
+
+			inferred = append(inferred, fdecl)
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, fdecl := range inferred {
+			fact := facts[pp.pass.TypesInfo.Defs[fdecl.Name].(*types.Func)]
+
+			for i, closer := range fact.closers {
+				if closer && !fact.borrowed[i] && pp.returnsBorrowed(fdecl, i, facts) {
+					fact.borrowed[i] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	for fn, fact := range facts {
+		pp.pass.ExportObjectFact(fn, fact)
+	}
+}
+
+// returnsBorrowed checks whether every return of the function returns a borrowed closer in the i-th result
+func (pp *FunctionVisitor) returnsBorrowed(fdecl *ast.FuncDecl, i int, facts map[*types.Func]*resultOwnership) bool {
+	borrowed := true
+	found := false
+
+	ast.Inspect(fdecl.Body, func(n ast.Node) bool {
+		switch castedNode := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			switch {
+			case len(castedNode.Results) == 0:
+				// named results
+				borrowed = false
+			case len(castedNode.Results) == 1 && i > 0:
+				call, ok := astutil.Unparen(castedNode.Results[0]).(*ast.CallExpr)
+				borrowed = borrowed && ok && pp.callReturnsBorrowed(call, i, facts)
+				found = true
+			case i < len(castedNode.Results):
+				expr := castedNode.Results[i]
+				if id, ok := astutil.Unparen(expr).(*ast.Ident); ok && id.Name == "nil" {
+					return true
+				}
+
+				borrowed = borrowed && pp.isBorrowed(fdecl, expr, facts)
+				found = true
+			}
+		}
+
+		return borrowed
+	})
+
+	return borrowed && found
+}
+
+// isBorrowed checks whether the closer returned by the expression is owned by someone else, like the fields of the
+// receiver and the global variables
+func (pp *FunctionVisitor) isBorrowed(fdecl *ast.FuncDecl, expr ast.Expr, facts map[*types.Func]*resultOwnership) bool {
+	switch castedExpr := astutil.Unparen(expr).(type) {
+	case *ast.SelectorExpr:
+		if selection, ok := pp.pass.TypesInfo.Selections[castedExpr]; ok {
+			return selection.Kind() == types.FieldVal && pp.isBorrowed(fdecl, castedExpr.X, facts)
+		}
+
+		// a global variable of another package
+		_, ok := pp.pass.TypesInfo.ObjectOf(castedExpr.Sel).(*types.Var)
+
+		return ok
+	case *ast.StarExpr:
+		return pp.isBorrowed(fdecl, castedExpr.X, facts)
+	case *ast.Ident:
+		obj, ok := pp.pass.TypesInfo.ObjectOf(castedExpr).(*types.Var)
+		if !ok {
+			return false
+		}
+
+		if fdecl.Recv != nil && len(fdecl.Recv.List[0].Names) > 0 &&
+			pp.pass.TypesInfo.Defs[fdecl.Recv.List[0].Names[0]] == obj {
+			return true
+		}
+
+		return obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope()
+	case *ast.CallExpr:
+		return pp.callReturnsBorrowed(castedExpr, 0, facts)
+	}
+
+	return false
+}
+
+func (pp *FunctionVisitor) callReturnsBorrowed(call *ast.CallExpr, i int, facts map[*types.Func]*resultOwnership) bool {
+	fn, ok := typeutil.Callee(pp.pass.TypesInfo, call).(*types.Func)
+	if !ok {
+		return false
+	}
+
+	if fact, ok := facts[fn]; ok {
+		return fact.isBorrowed(i)
+	}
+
+	return returnsBorrowedCloser(pp.pass, fn, i)
+}
+
+// returnsBorrowedCloser checks whether the i-th result of the function is a closer that the caller doesn't have to
+// release
+func returnsBorrowedCloser(pass *analysis.Pass, fn *types.Func, i int) bool {
+	fact := &resultOwnership{}
+
+	return fn != nil && pass.ImportObjectFact(fn, fact) && fact.isBorrowed(i)
+}
@@ -0,0 +1,481 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ssa"
+)
+
+// SSAVisitor is in charge of checking that the closers returned by calls are closed, following their values through
+// aliases, phi nodes, field stores and calls in the SSA form of the package
+type SSAVisitor struct {
+	pass     *analysis.Pass
+	settings *settings
+}
+
+// ssaRef is a value that holds a closer. When field is not -1 the closer is the field with that index of the struct
+// pointed by the value. When addr is true the value is the address of a variable that holds the closer.
+type ssaRef struct {
+	v     ssa.Value
+	field int
+	addr  bool
+}
+
+type ssaWalker struct {
+	sv   *SSAVisitor
+	rule *resourceRule
+	seen map[ssaRef]bool
+}
+
+// this function checks the calls that return a closer
+func (sv *SSAVisitor) checkCallsThatReturnCloser() {
+	ssaInput := sv.pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+
+	for _, fn := range ssaInput.SrcFuncs {
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				switch castedInstr := instr.(type) {
+				case *ssa.Call:
+					sv.checkCall(castedInstr)
+				case *ssa.Defer:
+					if sv.callReturnsCloser(castedInstr.Common()) {
+						sv.pass.Reportf(castedInstr.Pos(), "return value won't be closed because it's on defer statement") // FIXME: improve message
+					}
+				case *ssa.Go:
+					if sv.callReturnsCloser(castedInstr.Common()) {
+						sv.pass.Reportf(castedInstr.Pos(), "return value won't be closed because it's on go statement") // FIXME: improve message
+					}
+				}
+			}
+		}
+	}
+}
+
+func (sv *SSAVisitor) checkCall(call *ssa.Call) {
+	if sv.isNonOwningWrapper(call.Common()) {
+		return
+	}
+
+	results := call.Call.Signature().Results()
+	lhs, isExprStmt := sv.findAssignedNames(call)
+
+	for i := 0; i < results.Len(); i++ {
+		rv := sv.settings.rules.newReturnVar(results.At(i).Type())
+		if !rv.needsClosing || sv.returnsBorrowedCloser(call.Common(), i) {
+			continue
+		}
+
+		if isExprStmt {
+			sv.pass.Reportf(call.Pos(), "return value won't be closed because it wasn't assigned") // FIXME: improve message
+			return
+		}
+
+		v := sv.resultValue(call, i)
+		name := "_"
+
+		if i < len(lhs) {
+			name = lhs[i]
+		}
+
+		if len(rv.fields) == 0 && !sv.releases(v, -1, rv.rule) {
+			sv.pass.Reportf(call.Pos(), "%s (%s) was not %s", name, rv.typeName, rv.rule.action)
+		}
+
+		for _, field := range rv.fields {
+			if !sv.releases(v, field.index, field.rule) {
+				sv.pass.Reportf(call.Pos(), "%s (%s) was not %s", name+"."+field.name, field.typeName, field.rule.action)
+			}
+		}
+	}
+}
+
+func (sv *SSAVisitor) callReturnsCloser(common *ssa.CallCommon) bool {
+	if sv.isNonOwningWrapper(common) {
+		return false
+	}
+
+	results := common.Signature().Results()
+
+	for i := 0; i < results.Len(); i++ {
+		if sv.settings.rules.newReturnVar(results.At(i).Type()).needsClosing && !sv.returnsBorrowedCloser(common, i) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (sv *SSAVisitor) returnsBorrowedCloser(common *ssa.CallCommon, i int) bool {
+	callee := common.StaticCallee()
+	if callee == nil {
+		return false
+	}
+
+	fn, _ := callee.Object().(*types.Func)
+
+	return returnsBorrowedCloser(sv.pass, fn, i)
+}
+
+// resultValue returns the value of the i-th result of the call, or nil if it's discarded
+func (sv *SSAVisitor) resultValue(call *ssa.Call, i int) ssa.Value {
+	if call.Call.Signature().Results().Len() == 1 {
+		return call
+	}
+
+	for _, instr := range *call.Referrers() {
+		if extract, ok := instr.(*ssa.Extract); ok && extract.Index == i {
+			return extract
+		}
+	}
+
+	return nil
+}
+
+// findAssignedNames finds the names of the variables the results of the call are assigned to, and whether the call
+// is an expression statement
+func (sv *SSAVisitor) findAssignedNames(call *ssa.Call) ([]string, bool) {
+	for _, file := range sv.pass.Files {
+		if call.Pos() < file.Pos() || call.Pos() > file.End() {
+			continue
+		}
+
+		path, _ := astutil.PathEnclosingInterval(file, call.Pos(), call.Pos())
+
+		for i, node := range path {
+			callExpr, ok := node.(*ast.CallExpr)
+			if !ok || callExpr.Lparen != call.Pos() || i+1 >= len(path) {
+				continue
+			}
+
+			switch parent := path[i+1].(type) {
+			case *ast.ExprStmt:
+				return nil, true
+			case *ast.AssignStmt:
+				return assignedNames(parent.Lhs, parent.Rhs, callExpr), false
+			case *ast.ValueSpec:
+				lhs := make([]ast.Expr, 0, len(parent.Names))
+				for _, name := range parent.Names {
+					lhs = append(lhs, name)
+				}
+
+				return assignedNames(lhs, parent.Values, callExpr), false
+			}
+
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+func assignedNames(lhs []ast.Expr, rhs []ast.Expr, call *ast.CallExpr) []string {
+	if len(rhs) != 1 {
+		for i, expr := range rhs {
+			if expr == call && i < len(lhs) {
+				lhs = lhs[i : i+1]
+				break
+			}
+		}
+	}
+
+	names := make([]string, 0, len(lhs))
+
+	for _, expr := range lhs {
+		if id, ok := expr.(*ast.Ident); ok {
+			names = append(names, id.Name)
+		} else {
+			names = append(names, "_")
+		}
+	}
+
+	return names
+}
+
+func (sv *SSAVisitor) releases(v ssa.Value, field int, rule *resourceRule) bool {
+	if v == nil {
+		return false
+	}
+
+	w := &ssaWalker{sv: sv, rule: rule, seen: map[ssaRef]bool{}}
+	closed, returned := w.walk(ssaRef{v: v, field: field})
+
+	return closed || returned
+}
+
+// walk follows the value and its aliases, it returns whether the closer is closed or stored somewhere else and
+// whether it's returned by the function
+func (w *ssaWalker) walk(ref ssaRef) (closed bool, returned bool) {
+	queue := []ssaRef{ref}
+
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+
+		if ref.v == nil || w.seen[ref] {
+			continue
+		}
+
+		w.seen[ref] = true
+
+		referrers := ref.v.Referrers()
+		if referrers == nil {
+			continue
+		}
+
+		for _, instr := range *referrers {
+			aliases, isClosed, isReturned := w.follow(ref, instr)
+			if isClosed {
+				return true, returned
+			}
+
+			returned = returned || isReturned
+			queue = append(queue, aliases...)
+		}
+	}
+
+	return false, returned
+}
+
+func (w *ssaWalker) follow(ref ssaRef, instr ssa.Instruction) ([]ssaRef, bool, bool) {
+	if ref.addr {
+		return w.followAddr(ref, instr)
+	}
+
+	switch castedInstr := instr.(type) {
+	case *ssa.FieldAddr:
+		if ref.field == -1 || castedInstr.Field != ref.field {
+			return nil, false, false
+		}
+
+		aliases := []ssaRef{}
+
+		for _, load := range *castedInstr.Referrers() {
+			if unop, ok := load.(*ssa.UnOp); ok && unop.Op == token.MUL {
+				aliases = append(aliases, ssaRef{v: unop, field: -1})
+			}
+		}
+
+		return aliases, false, false
+	case *ssa.Phi, *ssa.ChangeType, *ssa.ChangeInterface, *ssa.MakeInterface, *ssa.TypeAssert, *ssa.Convert:
+		return []ssaRef{{v: castedInstr.(ssa.Value), field: ref.field}}, false, false
+	case *ssa.Store:
+		if castedInstr.Val != ref.v {
+			return nil, false, false
+		}
+
+		if _, ok := castedInstr.Addr.(*ssa.Alloc); ok {
+			return []ssaRef{{v: castedInstr.Addr, field: ref.field, addr: true}}, false, false
+		}
+
+		// stored in a field, a global variable or a collection
+		return nil, true, false
+	case *ssa.Send, *ssa.MapUpdate:
+		return nil, true, false
+	case *ssa.Return:
+		return nil, false, true
+	case ssa.CallInstruction:
+		return w.followCall(ref, castedInstr)
+	}
+
+	return nil, false, false
+}
+
+// followAddr follows the address of a local variable that holds the closer, like the ones captured by closures
+func (w *ssaWalker) followAddr(ref ssaRef, instr ssa.Instruction) ([]ssaRef, bool, bool) {
+	switch castedInstr := instr.(type) {
+	case *ssa.UnOp:
+		if castedInstr.Op == token.MUL {
+			return []ssaRef{{v: castedInstr, field: ref.field}}, false, false
+		}
+	case *ssa.MakeClosure:
+		fn := castedInstr.Fn.(*ssa.Function)
+		aliases := []ssaRef{}
+
+		for i, binding := range castedInstr.Bindings {
+			if binding == ref.v {
+				aliases = append(aliases, ssaRef{v: fn.FreeVars[i], field: ref.field, addr: true})
+			}
+		}
+
+		return aliases, false, false
+	case ssa.CallInstruction:
+		// the address of the variable is passed to another function, it's only closed if the function closes it
+		return nil, w.callReleasesAddr(ref, castedInstr.Common()), false
+	}
+
+	return nil, false, false
+}
+
+// callReleasesAddr checks whether the function called releases the closer whose address is passed to it, either
+// because of its facts or because its parameter is closed through the address, like `closeFile(&f)`
+func (w *ssaWalker) callReleasesAddr(ref ssaRef, common *ssa.CallCommon) bool {
+	callee := common.StaticCallee()
+	if callee == nil {
+		return false
+	}
+
+	for i, arg := range common.Args {
+		if arg != ref.v {
+			continue
+		}
+
+		if obj, ok := callee.Object().(*types.Func); ok && w.sv.settings.isReleaseFunction(obj.FullName()) {
+			return true
+		}
+
+		if w.sv.takesOwnership(callee, i) || w.sv.releasesParam(callee, i) {
+			return true
+		}
+
+		if len(callee.Blocks) == 0 || i >= len(callee.Params) {
+			continue
+		}
+
+		if closed, _ := w.walk(ssaRef{v: callee.Params[i], field: ref.field, addr: true}); closed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *ssaWalker) followCall(ref ssaRef, call ssa.CallInstruction) ([]ssaRef, bool, bool) {
+	common := call.Common()
+
+	if common.IsInvoke() {
+		return nil, ref.field == -1 && common.Value == ref.v && w.rule.isReleaseMethod(common.Method.Name()), false
+	}
+
+	if ref.field == -1 && common.Value == ref.v && len(w.rule.methods) == 0 {
+		// the resource is released by calling it, like context.CancelFunc
+		return nil, true, false
+	}
+
+	callee := common.StaticCallee()
+	if callee == nil {
+		return nil, false, false
+	}
+
+	if ref.field == -1 && callee.Signature.Recv() != nil && w.rule.isReleaseMethod(callee.Name()) &&
+		len(common.Args) > 0 && common.Args[0] == ref.v {
+		return nil, true, false
+	}
+
+	aliases := []ssaRef{}
+
+	for i, arg := range common.Args {
+		if arg != ref.v {
+			continue
+		}
+
+		if obj, ok := callee.Object().(*types.Func); ok && w.sv.settings.isReleaseFunction(obj.FullName()) {
+			return nil, true, false
+		}
+
+		if w.sv.takesOwnership(callee, i) {
+			return nil, true, false
+		}
+
+		if obj, ok := callee.Object().(*types.Func); ok && ref.field == -1 && w.sv.settings.isOwningWrapper(obj.FullName()) {
+			// the closer is released along with the wrapper
+			aliases = append(aliases, ssaRef{v: wrapperOf(call), field: -1})
+			continue
+		}
+
+		if len(callee.Blocks) == 0 {
+			// the function is declared in another package
+			if w.sv.releasesParam(callee, i) {
+				return nil, true, false
+			}
+
+			continue
+		}
+
+		if i >= len(callee.Params) {
+			continue
+		}
+
+		closed, returned := w.walk(ssaRef{v: callee.Params[i], field: ref.field})
+		if closed {
+			return nil, true, false
+		}
+
+		if value, ok := call.(*ssa.Call); ok && returned {
+			aliases = append(aliases, ssaRef{v: value, field: ref.field})
+		}
+	}
+
+	return aliases, false, false
+}
+
+// releasesParam checks whether the function releases the closer passed in the i-th argument of a call to it
+func (sv *SSAVisitor) releasesParam(fn *ssa.Function, i int) bool {
+	obj, ok := fn.Object().(*types.Func)
+	if !ok {
+		return false
+	}
+
+	if fn.Signature.Recv() != nil {
+		// the receiver is the first argument
+		i--
+	}
+
+	cl := &ioCloserFunc{}
+
+	return sv.pass.ImportObjectFact(obj, cl) && cl.releasesParam(i)
+}
+
+// takesOwnership checks whether the i-th argument of a call to the function is annotated with takes-ownership
+func (sv *SSAVisitor) takesOwnership(fn *ssa.Function, i int) bool {
+	obj, ok := fn.Object().(*types.Func)
+	if !ok {
+		return false
+	}
+
+	if fn.Signature.Recv() != nil {
+		i--
+	}
+
+	cl := &ioCloserFunc{}
+
+	return sv.pass.ImportObjectFact(obj, cl) && i >= 0 && i < len(cl.takesOwnership) && cl.takesOwnership[i]
+}
+
+// isNonOwningWrapper checks whether the closers returned by the call don't have to be released, like the one of
+// `io.NopCloser(r)`
+func (sv *SSAVisitor) isNonOwningWrapper(common *ssa.CallCommon) bool {
+	callee := common.StaticCallee()
+	if callee == nil {
+		return false
+	}
+
+	fn, ok := callee.Object().(*types.Func)
+
+	return ok && sv.settings.isNonOwningWrapper(fn.FullName())
+}
+
+// wrapperOf returns the value of the wrapper returned by a call to an owning wrapper, like the reader of
+// `bufio.NewReader(f)`
+func wrapperOf(call ssa.CallInstruction) ssa.Value {
+	value, ok := call.(*ssa.Call)
+	if !ok {
+		return nil
+	}
+
+	if _, isTuple := value.Type().(*types.Tuple); !isTuple {
+		return value
+	}
+
+	for _, instr := range *value.Referrers() {
+		if extract, ok := instr.(*ssa.Extract); ok && extract.Index == 0 {
+			return extract
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// releasedFields is the fact exported for the resource types that wrap other closers in their fields, it lists the
+// fields that are released by the release methods of the type
+type releasedFields struct {
+	method string
+	fields []string
+}
+
+func (f *releasedFields) AFact() {}
+
+// releasedFieldsData is how releasedFields is encoded
+type releasedFieldsData struct {
+	Method string
+	Fields []string
+}
+
+func (f *releasedFields) GobEncode() ([]byte, error) {
+	return encodeFact(releasedFieldsData{Method: f.method, Fields: f.fields})
+}
+
+func (f *releasedFields) GobDecode(b []byte) error {
+	data := releasedFieldsData{}
+	if err := decodeFact(b, &data); err != nil {
+		return err
+	}
+
+	f.method, f.fields = data.Method, data.Fields
+
+	return nil
+}
+
+// String is the string representation of the fact
+func (f *releasedFields) String() string {
+	if len(f.fields) == 0 {
+		return fmt.Sprintf("%s releases no fields", f.method)
+	}
+
+	return fmt.Sprintf("%s releases %s", f.method, strings.Join(f.fields, ", "))
+}
+
+func (f *releasedFields) releases(field *types.Var) bool {
+	for _, name := range f.fields {
+		if name == field.Name() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findTypesThatReleaseFields finds the fields released by the release methods of the resource types of the package,
+// like `c.resp.Body.Close()` in the Close method of a type that wraps an http.Response
+func (pp *FunctionVisitor) findTypesThatReleaseFields() {
+	facts := map[*types.TypeName]*releasedFields{}
+
+	for _, file := range pp.pass.Files {
+		for _, decl := range file.Decls {
+			fdecl, ok := decl.(*ast.FuncDecl)
+			if !ok || fdecl.Recv == nil || fdecl.Body == nil || len(fdecl.Recv.List[0].Names) == 0 {
+				continue
+			}
+
+			recv := pp.pass.TypesInfo.Defs[fdecl.Recv.List[0].Names[0]]
+			if recv == nil {
+				continue
+			}
+
+			named, str := wrapperStruct(recv.Type())
+			if str == nil || !pp.hasCloserFields(str) {
+				continue
+			}
+
+			rule := pp.settings.rules.find(types.NewPointer(named))
+			if rule == nil || !rule.isReleaseMethod(fdecl.Name.Name) {
+				continue
+			}
+
+			fact, ok := facts[named.Obj()]
+			if !ok {
+				fact = &releasedFields{method: fdecl.Name.Name}
+				facts[named.Obj()] = fact
+			}
+
+			for i := 0; i < str.NumFields(); i++ {
+				field := str.Field(i)
+
+				if pp.settings.rules.newReturnVar(field.Type()).needsClosing && !fact.releases(field) &&
+					pp.releasesField(fdecl.Body, recv, field) {
+					fact.fields = append(fact.fields, field.Name())
+				}
+			}
+		}
+	}
+
+	for obj, fact := range facts {
+		pp.pass.ExportObjectFact(obj, fact)
+	}
+}
+
+func (pp *FunctionVisitor) hasCloserFields(str *types.Struct) bool {
+	for i := 0; i < str.NumFields(); i++ {
+		if pp.settings.rules.newReturnVar(str.Field(i).Type()).needsClosing {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wrapperStruct returns the named type and the struct of a type like T or *T
+func wrapperStruct(t types.Type) (*types.Named, *types.Struct) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	named, ok := t.(*types.Named)
+	if !ok {
+		return nil, nil
+	}
+
+	str, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, nil
+	}
+
+	return named, str
+}
+
+// releasesField checks whether the body releases the field of the receiver, or a closer inside it
+func (pp *FunctionVisitor) releasesField(body *ast.BlockStmt, recv types.Object, field *types.Var) bool {
+	released := false
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || released {
+			return !released
+		}
+
+		if sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr); ok && pp.isFieldOf(sel.X, recv, field) {
+			rv := pp.settings.rules.newReturnVar(pp.pass.TypesInfo.TypeOf(sel.X))
+			if rv.rule != nil && rv.rule.isReleaseMethod(sel.Sel.Name) {
+				released = true
+			}
+		}
+
+		if pp.isFieldOf(call.Fun, recv, field) {
+			// the resource is released by calling it, like context.CancelFunc
+			rv := pp.settings.rules.newReturnVar(pp.pass.TypesInfo.TypeOf(call.Fun))
+			if rv.rule != nil && len(rv.rule.methods) == 0 {
+				released = true
+			}
+		}
+
+		if pp.callReleases(call, func(expr ast.Expr) bool { return pp.isFieldOf(expr, recv, field) }) {
+			released = true
+		}
+
+		return !released
+	})
+
+	return released
+}
+
+// isFieldOf checks whether the expression is the field of the receiver, or a selector inside it like `c.resp.Body`
+func (pp *FunctionVisitor) isFieldOf(expr ast.Expr, recv types.Object, field *types.Var) bool {
+	sel, ok := astutil.Unparen(expr).(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	if id, ok := astutil.Unparen(sel.X).(*ast.Ident); ok && pp.pass.TypesInfo.ObjectOf(id) == recv {
+		return pp.pass.TypesInfo.ObjectOf(sel.Sel) == field
+	}
+
+	return pp.isFieldOf(sel.X, recv, field)
+}
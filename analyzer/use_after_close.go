@@ -0,0 +1,240 @@
+package analyzer
+
+import (
+	"go/ast"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/cfg"
+)
+
+// methodsValidAfterClose are the methods that only return what is known about the closer, like the name of a file
+// or the address of a connection, so they can be called after the closer is closed
+var methodsValidAfterClose = map[string]bool{
+	"Name":       true,
+	"Fd":         true,
+	"LocalAddr":  true,
+	"RemoteAddr": true,
+}
+
+// checkUseAfterClose reports the uses of the closer that are reached after it's closed on every path, like reading
+// `res.Body` after `res.Body.Close()`. Only the calls to Close that aren't deferred are taken into account, the other
+// release methods, like Flush or Stop, don't prevent the resource from being used again.
+func (av *AssignVisitor) checkUseAfterClose(g *cfg.CFG, idToClose *posToClose) bool {
+	if idToClose.obj == nil || !idToClose.rule.isReleaseMethod("Close") {
+		return true
+	}
+
+	region := av.reachableBlocks(idToClose.block)
+	preds := map[*cfg.Block][]*cfg.Block{}
+
+	for _, block := range g.Blocks {
+		if !block.Live {
+			continue
+		}
+
+		for _, succ := range block.Succs {
+			preds[succ] = append(preds[succ], block)
+		}
+	}
+
+	// closedOut tells whether the closer is closed on every path that leaves the block, it starts as true for every
+	// block and it's refined until nothing changes
+	closedOut := map[*cfg.Block]bool{}
+	for block := range region {
+		closedOut[block] = true
+	}
+
+	closedIn := func(block *cfg.Block) bool {
+		if len(preds[block]) == 0 {
+			return false
+		}
+
+		for _, pred := range preds[block] {
+			if !region[pred] || !closedOut[pred] {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, block := range g.Blocks {
+			if !region[block] {
+				continue
+			}
+
+			if closed := av.closedAfter(idToClose, block, closedIn(block), nil); closed != closedOut[block] {
+				closedOut[block] = closed
+				changed = true
+			}
+		}
+	}
+
+	ok := true
+
+	for _, block := range g.Blocks {
+		if !region[block] {
+			continue
+		}
+
+		av.closedAfter(idToClose, block, closedIn(block), func(use ast.Expr) {
+			ok = false
+
+			av.pass.Reportf(use.Pos(), "%s (%s) is used after being %s", idToClose.name, idToClose.typeName,
+				idToClose.rule.action)
+		})
+	}
+
+	return ok
+}
+
+// reachableBlocks returns the blocks that can be reached from the given one, including itself when it's in a loop
+func (av *AssignVisitor) reachableBlocks(block *cfg.Block) map[*cfg.Block]bool {
+	region := map[*cfg.Block]bool{}
+	queue := append([]*cfg.Block{}, block.Succs...)
+
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		if region[next] || !next.Live {
+			continue
+		}
+
+		region[next] = true
+		queue = append(queue, next.Succs...)
+	}
+
+	region[block] = true
+
+	return region
+}
+
+// closedAfter runs the nodes of the block and returns whether the closer is closed at the end of it, onUse is called
+// for the uses of the closer found while it's closed
+func (av *AssignVisitor) closedAfter(idToClose *posToClose, block *cfg.Block, closed bool, onUse func(ast.Expr)) bool {
+	for i, node := range block.Nodes {
+		if block == idToClose.block && i == idToClose.index {
+			closed = false
+			continue
+		}
+
+		if closed && onUse != nil {
+			for _, use := range av.usesOfCloser(idToClose, node) {
+				onUse(use)
+			}
+		}
+
+		switch {
+		case av.closesDirectly(idToClose, node):
+			closed = true
+		case av.assignsObject(idToClose, node):
+			closed = false
+		}
+	}
+
+	return closed
+}
+
+// closesDirectly checks whether the node is a call to Close on the closer that isn't deferred, like `f.Close()` or
+// `err = f.Close()`
+func (av *AssignVisitor) closesDirectly(idToClose *posToClose, node ast.Node) bool {
+	var expr ast.Expr
+
+	switch castedNode := node.(type) {
+	case *ast.ExprStmt:
+		expr = castedNode.X
+	case *ast.AssignStmt:
+		if len(castedNode.Rhs) != 1 {
+			return false
+		}
+
+		expr = castedNode.Rhs[0]
+	default:
+		return false
+	}
+
+	call, ok := astutil.Unparen(expr).(*ast.CallExpr)
+
+	return ok && av.isCloseCall(idToClose, call)
+}
+
+func (av *AssignVisitor) isCloseCall(idToClose *posToClose, call *ast.CallExpr) bool {
+	sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+
+	return ok && sel.Sel.Name == "Close" && av.isCloserExpr(idToClose, sel.X)
+}
+
+// isValidAfterClose checks whether the call is to a method of the closer that doesn't do any I/O, so it can be
+// called after the closer is closed, like `f.Name()`
+func (av *AssignVisitor) isValidAfterClose(idToClose *posToClose, call *ast.CallExpr) bool {
+	sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+
+	return ok && methodsValidAfterClose[sel.Sel.Name] && av.isCloserExpr(idToClose, sel.X)
+}
+
+// assignsObject checks whether the node assigns a new value to the variable that holds the closer, or to the field of
+// the closer, like `res.Body = body`
+func (av *AssignVisitor) assignsObject(idToClose *posToClose, node ast.Node) bool {
+	assign, ok := node.(*ast.AssignStmt)
+	if !ok {
+		return false
+	}
+
+	for _, lhs := range assign.Lhs {
+		if av.isObject(lhs, idToClose.obj) || av.isCloserExpr(idToClose, lhs) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// usesOfCloser returns the expressions of the node that use the closer. The calls to Close, the calls to the methods
+// that are valid after closing, the comparisons with nil and the assignments of new values aren't uses, and neither
+// are the function literals since they can run later.
+func (av *AssignVisitor) usesOfCloser(idToClose *posToClose, node ast.Node) []ast.Expr {
+	if _, ok := node.(*ast.RangeStmt); ok {
+		// the range expression is a node of its own
+		return nil
+	}
+
+	uses := []ast.Expr{}
+
+	var visit func(n ast.Node) bool
+
+	visit = func(n ast.Node) bool {
+		switch castedNode := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if av.isCloseCall(idToClose, castedNode) || av.isValidAfterClose(idToClose, castedNode) {
+				return false
+			}
+		case *ast.BinaryExpr:
+			if x, _ := comparedWithNil(av.pass.TypesInfo, castedNode); x != nil && av.isCloserExpr(idToClose, x) {
+				return false
+			}
+		case *ast.AssignStmt:
+			for _, rhs := range castedNode.Rhs {
+				ast.Inspect(rhs, visit)
+			}
+
+			return false
+		case ast.Expr:
+			if av.isCloserExpr(idToClose, castedNode) {
+				uses = append(uses, castedNode)
+				return false
+			}
+		}
+
+		return true
+	}
+
+	ast.Inspect(node, visit)
+
+	return uses
+}
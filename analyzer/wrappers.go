@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// defaultNonOwningWrappers are the functions that return closers that don't hold any resource, so they don't have to
+// be released, like `io.NopCloser(r)`
+var defaultNonOwningWrappers = []string{
+	"io.NopCloser",
+	"io/ioutil.NopCloser",
+	"(*net/http/httptest.ResponseRecorder).Result",
+}
+
+// defaultOwningWrappers are the functions that wrap the closer they receive, the result owns the closer so the
+// closer is released along with the result, like `bufio.NewReader(f)`. The readers and writers of compress/... aren't
+// owning wrappers, their Close methods don't close the underlying reader or writer.
+var defaultOwningWrappers = []string{
+	"bufio.NewReader",
+	"bufio.NewReaderSize",
+	"bufio.NewWriter",
+	"bufio.NewWriterSize",
+	"bufio.NewScanner",
+}
+
+// isNonOwningWrapper checks whether the closers returned by the call don't have to be released
+func (av *AssignVisitor) isNonOwningWrapper(call *ast.CallExpr) bool {
+	fn, ok := typeutil.Callee(av.pass.TypesInfo, call).(*types.Func)
+
+	return ok && av.settings.isNonOwningWrapper(fn.FullName())
+}
+
+// isOwningWrapper checks whether the call wraps the closers passed to it, like `bufio.NewReader(f)`
+func (av *AssignVisitor) isOwningWrapper(call *ast.CallExpr) bool {
+	fn, ok := typeutil.Callee(av.pass.TypesInfo, call).(*types.Func)
+
+	return ok && av.settings.isOwningWrapper(fn.FullName())
+}
+
+// ownerAssigned returns the local variable that owns the closer after the node, or nil if the node doesn't give the
+// closer to another value. The owner is either a wrapper, like r in `r := bufio.NewReader(f)`, or a local struct that
+// holds the closer, like c in `c := &Conn{f: f}` or `c.f = f`. The paths of the closer go on after the assignment, and
+// the closer is released along with the owner from then on.
+func (av *AssignVisitor) ownerAssigned(idToClose *posToClose, node ast.Node) types.Object {
+	assign, ok := node.(*ast.AssignStmt)
+	if !ok || len(assign.Lhs) != len(assign.Rhs) && len(assign.Rhs) != 1 {
+		return nil
+	}
+
+	for i, rhs := range assign.Rhs {
+		if i >= len(assign.Lhs) {
+			break
+		}
+
+		var owner ast.Expr
+
+		switch castedRhs := astutil.Unparen(rhs).(type) {
+		case *ast.CallExpr:
+			// the wrapper is the first result, like the decoder of `dec, err := codec.NewDecoder(f)`
+			if av.isOwningWrapper(castedRhs) && av.isPosInAnyExpression(idToClose, castedRhs.Args) {
+				owner = assign.Lhs[i]
+			}
+		case *ast.UnaryExpr, *ast.CompositeLit:
+			if av.holdsCloser(idToClose, castedRhs) {
+				owner = assign.Lhs[i]
+			}
+		default:
+			sel, ok := assign.Lhs[i].(*ast.SelectorExpr)
+			if !ok || !av.isCloserExpr(idToClose, rhs) {
+				continue
+			}
+
+			if field, ok := av.pass.TypesInfo.ObjectOf(sel.Sel).(*types.Var); ok && field.IsField() {
+				av.checkStoredInField(idToClose, av.pass.TypesInfo.TypeOf(sel.X), field, rhs)
+			}
+
+			owner = sel.X
+		}
+
+		if obj := av.localObject(owner); obj != nil && obj != idToClose.obj {
+			return obj
+		}
+	}
+
+	return nil
+}
+
+// holdsCloser checks whether the composite literal, or its address, has the closer in one of its fields
+func (av *AssignVisitor) holdsCloser(idToClose *posToClose, expr ast.Expr) bool {
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = astutil.Unparen(unary.X)
+	}
+
+	lit, ok := expr.(*ast.CompositeLit)
+
+	return ok && av.isPosInExpression(idToClose, lit)
+}
+
+// localObject returns the local variable of the expression, or nil if it isn't one
+func (av *AssignVisitor) localObject(expr ast.Expr) types.Object {
+	id, ok := expr.(*ast.Ident)
+	if !ok || isBlank(id) {
+		return nil
+	}
+
+	obj, ok := av.pass.TypesInfo.ObjectOf(id).(*types.Var)
+	if !ok || obj.Pkg() == nil || obj.Parent() == obj.Pkg().Scope() {
+		return nil
+	}
+
+	return obj
+}
+
+// ownerToClose returns the closer to check for the value that owns the closer
+func ownerToClose(idToClose *posToClose, owner types.Object) *posToClose {
+	inner := *idToClose
+	inner.obj, inner.field, inner.name, inner.errObj = owner, nil, owner.Name(), nil
+
+	return &inner
+}
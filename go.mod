@@ -2,4 +2,7 @@ module github.com/dcu/closecheck
 
 go 1.14
 
-require golang.org/x/tools v0.19.0
+require (
+	golang.org/x/tools v0.19.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -16,10 +16,13 @@ limitations under the License.
 package main
 
 import (
+	"os"
+
 	"github.com/dcu/closecheck/analyzer"
 	"golang.org/x/tools/go/analysis/singlechecker"
 )
 
 func main() {
-	singlechecker.Main(analyzer.Analyzer)
+	// the engine decides the analyzers that are required, so it's chosen before the flags are parsed
+	singlechecker.Main(analyzer.New(analyzer.Options{Engine: os.Getenv("CLOSECHECK_ENGINE")}))
 }
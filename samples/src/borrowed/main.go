@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net"
+)
+
+var defaultConn net.Conn
+
+type Client struct {
+	conn  net.Conn
+	conns map[string]net.Conn
+}
+
+func Dial(addr string) (net.Conn, error) { // want Dial:"result 0 owned"
+	return net.Dial("tcp", addr)
+}
+
+func (c *Client) Conn() net.Conn { // want Conn:"result 0 borrowed"
+	return c.conn
+}
+
+func (c *Client) ConnOrDefault() net.Conn { // want ConnOrDefault:"result 0 borrowed"
+	if c == nil {
+		return defaultConn
+	}
+
+	return c.Conn()
+}
+
+func (c *Client) Lookup(name string) (net.Conn, bool) { // want Lookup:"result 0 borrowed"
+	if c.conns == nil {
+		return nil, false
+	}
+
+	return c.conn, true
+}
+
+// ConnFor returns one of the connections of the client, they are closed by the client.
+//
+//closecheck:returns-borrowed
+func (c *Client) ConnFor(name string) net.Conn { // want ConnFor:"result 0 borrowed"
+	return c.conns[name]
+}
+
+// Detach returns the connection of the client, the caller has to close it.
+//
+//closecheck:returns-owned
+func (c *Client) Detach() net.Conn { // want Detach:"no closers" Detach:"result 0 owned"
+	defer func() {
+		c.conn = nil
+	}()
+
+	return c.conn
+}
+
+func main() {
+	conn, err := Dial("localhost:8080")
+	if err != nil {
+		panic(err)
+	}
+
+	defer conn.Close()
+
+	c := &Client{conn: conn}
+
+	println(c.Conn().LocalAddr().String())
+	println(c.ConnOrDefault().LocalAddr().String())
+	println(c.ConnFor("main").LocalAddr().String())
+
+	if shared, ok := c.Lookup("main"); ok {
+		println(shared.LocalAddr().String())
+	}
+
+	detached := c.Detach() // want `detached \(net.Conn\) was not closed`
+	println(detached.LocalAddr().String())
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+func closedInOneBranch(flag bool) {
+	res, err := http.Get("https://www.google.com") // want `res.Body \(io.ReadCloser\) was not closed on return at line 19`
+	if err != nil {
+		panic(err)
+	}
+
+	if flag {
+		_ = res.Body.Close()
+	} else {
+		println("not closed")
+	}
+}
+
+func closedInEveryBranch(flag bool) {
+	res, err := http.Get("https://www.google.com")
+	if err != nil {
+		panic(err)
+	}
+
+	if flag {
+		_ = res.Body.Close()
+	} else {
+		defer res.Body.Close()
+	}
+}
+
+func earlyReturn() error {
+	res, err := http.Get("https://www.google.com") // want `res.Body \(io.ReadCloser\) was not closed on return at line 41`
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return errors.New("unexpected status")
+	}
+
+	return res.Body.Close()
+}
+
+func closedWhenThereIsNoError() {
+	res, err := http.Get("https://www.google.com")
+	if err == nil {
+		defer res.Body.Close()
+	}
+}
+
+func closedWhenNotNil() {
+	res, _ := http.Get("https://www.google.com")
+	if res != nil {
+		defer res.Body.Close()
+	}
+}
+
+func closesAnotherResponse() {
+	res1, _ := http.Get("https://www.google.com") // want `res1.Body \(io.ReadCloser\) was not closed on return at line 68`
+	res2, _ := http.Get("https://www.google.com")
+
+	println(res1.StatusCode)
+
+	defer res2.Body.Close()
+}
+
+func main() {
+	closedInOneBranch(true)
+	closedInEveryBranch(true)
+	_ = earlyReturn()
+	closedWhenThereIsNoError()
+	closedWhenNotNil()
+	closesAnotherResponse()
+}
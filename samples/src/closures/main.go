@@ -0,0 +1,149 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+func run(fn func()) {
+	fn()
+}
+
+func closedByGoroutine(wg *sync.WaitGroup) {
+	res, err := http.Get("https://www.google.com")
+	if err != nil {
+		return
+	}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		if res.StatusCode == http.StatusOK {
+			_, _ = io.ReadAll(res.Body)
+		}
+
+		_ = res.Body.Close()
+	}()
+}
+
+func closedByGoroutineArgument() {
+	res, err := http.Get("https://www.google.com")
+	if err != nil {
+		return
+	}
+
+	go func(body io.Closer) {
+		_ = body.Close()
+	}(res.Body)
+}
+
+func closureThatDoesntClose() {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 53`
+	if err != nil {
+		return
+	}
+
+	run(func() {
+		println(f.Name())
+	})
+}
+
+func closureThatCloses() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	run(func() {
+		_ = f.Close()
+	})
+}
+
+func deferredClosureThatClosesAnotherFile() {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 79`
+	if err != nil {
+		return
+	}
+
+	g := os.Stdin
+
+	defer func() {
+		_ = g.Close()
+	}()
+
+	println(f.Name())
+}
+
+func storedClosure() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	cleanup := func() {
+		_ = f.Close()
+	}
+
+	defer cleanup()
+}
+
+func closureNeverCalled() {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 107`
+	if err != nil {
+		return
+	}
+
+	cleanup := func() { // want `f \(\*os.File\) is closed by the closure stored in cleanup, but cleanup is never called`
+		_ = f.Close()
+	}
+
+	if cleanup == nil {
+		println("no cleanup")
+	}
+}
+
+func closedConditionallyByClosure(done bool) {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 122`
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if done {
+			_ = f.Close()
+		}
+	}()
+
+	println(f.Name())
+}
+
+func closedByNilCheckingClosure() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if f != nil {
+			_ = f.Close()
+		}
+	}()
+
+	println(f.Name())
+}
+
+func main() {
+	closedByGoroutine(&sync.WaitGroup{})
+	closedByGoroutineArgument()
+	closureThatDoesntClose()
+	closureThatCloses()
+	deferredClosureThatClosesAnotherFile()
+	storedClosure()
+	closureNeverCalled()
+	closedConditionallyByClosure(true)
+	closedByNilCheckingClosure()
+}
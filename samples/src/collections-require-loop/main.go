@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+type Pool struct {
+	files []*os.File
+}
+
+func (p *Pool) Add(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+
+	p.files = append(p.files, f)
+
+	return nil
+}
+
+func (p *Pool) Close() {
+	for _, f := range p.files {
+		_ = f.Close()
+	}
+}
+
+func workers(names []string) {
+	ch := make(chan *os.File)
+	wg := sync.WaitGroup{}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for f := range ch {
+			println(f.Name())
+			_ = f.Close()
+		}
+	}()
+
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+
+		ch <- f
+	}
+
+	close(ch)
+	wg.Wait()
+}
+
+func leakInSlice(names []string) int {
+	files := []*os.File{}
+
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, f) // want `f \(\*os.File\) is stored in files, but the elements of files aren't closed in a loop`
+	}
+
+	for _, f := range files {
+		println(f.Name())
+	}
+
+	return len(files)
+}
+
+func leakInMap(names []string) int {
+	files := map[string]*os.File{}
+
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+
+		files[name] = f // want `f \(\*os.File\) is stored in files, but the elements of files aren't closed in a loop`
+	}
+
+	return len(files)
+}
+
+func openAll(names []string) []*os.File {
+	files := []*os.File{}
+
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, f)
+	}
+
+	return files
+}
+
+func (p *Pool) AddAll(names []string) {
+	files := []*os.File{}
+
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, f)
+	}
+
+	p.files = files
+}
+
+func main() {
+	p := &Pool{}
+	_ = p.Add("main.go")
+	p.Close()
+
+	workers([]string{"main.go"})
+	_ = leakInSlice([]string{"main.go"})
+	_ = leakInMap([]string{"main.go"})
+
+	for _, f := range openAll([]string{"main.go"}) {
+		_ = f.Close()
+	}
+
+	p.AddAll([]string{"main.go"})
+	p.Close()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net"
+	"os"
+)
+
+func sendToWorkers(names []string, files chan<- *os.File) {
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+
+		files <- f
+	}
+}
+
+func openAll(names []string) []*os.File {
+	files := []*os.File{}
+
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, f)
+	}
+
+	return files
+}
+
+func dialAll(addrs []string) map[string]net.Conn {
+	conns := map[string]net.Conn{}
+
+	for _, addr := range addrs {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			continue
+		}
+
+		conns[addr] = conn
+	}
+
+	return conns
+}
+
+func openAndForget(names []string) {
+	for _, name := range names {
+		f, err := os.Open(name) // want `f \(\*os.File\) is overwritten on the next iteration of the loop without being closed` `f \(\*os.File\) was not closed on return at line 58`
+		if err != nil {
+			continue
+		}
+
+		println(f.Name())
+	}
+}
+
+func main() {
+	files := make(chan *os.File, 1)
+	sendToWorkers([]string{"main.go"}, files)
+
+	for _, f := range openAll([]string{"main.go"}) {
+		_ = f.Close()
+	}
+
+	for _, conn := range dialAll([]string{"localhost:8080"}) {
+		_ = conn.Close()
+	}
+
+	openAndForget([]string{"main.go"})
+}
@@ -0,0 +1,20 @@
+package main
+
+import "os"
+
+// this package is excluded by the configuration, so its leaks and the problems of its annotations aren't reported
+
+//closecheck:takes-ownership g
+func keep(f *os.File) { // want keep:"f not closed"
+	_ = f
+}
+
+func main() {
+	f, err := os.Open("file.txt")
+	if err != nil {
+		return
+	}
+
+	_ = f
+	keep(nil)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// Conn, Handle and Lease are resources because they are listed in samples/config/closecheck.yaml
+
+type Conn struct{}
+
+func (c *Conn) Release() {}
+
+func (c *Conn) Query() {}
+
+type Handle struct{}
+
+func (h *Handle) Unlock() {}
+
+// Lease is an io.Closer too, but the configuration says that it's released by Release
+type Lease struct{}
+
+func (l *Lease) Release() {}
+
+func (l *Lease) Close() error { return nil }
+
+// Decoder owns the file it reads, newDecoder is listed as an owning wrapper
+type Decoder struct { // want Decoder:"Close releases f"
+	f *os.File
+}
+
+func (d *Decoder) Close() error { return d.f.Close() }
+
+func newDecoder(f *os.File) (*Decoder, error) { // want newDecoder:"f returned" newDecoder:"result 0 owned"
+	if f == nil {
+		return nil, errors.New("no file")
+	}
+
+	return &Decoder{f: f}, nil
+}
+
+var idle []*Conn
+
+func dial() *Conn { // want dial:"result 0 owned"
+	return &Conn{}
+}
+
+func lock() *Handle { // want lock:"result 0 owned"
+	return &Handle{}
+}
+
+// recycle is a release function of the configuration
+func recycle(c *Conn) { // want recycle:"c stored"
+	idle = append(idle, c)
+}
+
+func lease() *Lease { // want lease:"result 0 owned"
+	return &Lease{}
+}
+
+// giveBack is a release function of the configuration, it doesn't release the connection by itself
+func giveBack(c *Conn) { // want giveBack:"c not closed"
+	println(c)
+}
+
+func connNotReleased() {
+	c := dial() // want `c \(\*config-resources.Conn\) was not released on return at line 70`
+
+	c.Query()
+}
+
+func connReleased() {
+	c := dial()
+	defer c.Release()
+
+	c.Query()
+}
+
+func connRecycled() {
+	c := dial()
+	defer recycle(c)
+
+	c.Query()
+}
+
+func connGivenBack() {
+	c := dial()
+	defer giveBack(c)
+
+	c.Query()
+}
+
+func leaseReleased() {
+	l := lease()
+	defer l.Release()
+}
+
+func handleNotUnlocked() {
+	h := lock() // want `h \(\*config-resources.Handle\) was not released on return at line 104`
+
+	if h == nil {
+		return
+	}
+}
+
+func handleUnlocked() {
+	h := lock()
+	h.Unlock()
+}
+
+func decodeLeakingOnError() error {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 119`
+	if err != nil {
+		return err
+	}
+
+	dec, err := newDecoder(f)
+	if err != nil {
+		return err
+	}
+
+	return dec.Close()
+}
+
+func decodeClosingOnError() error {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return err
+	}
+
+	dec, err := newDecoder(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return dec.Close()
+}
+
+func main() {
+	connNotReleased()
+	connReleased()
+	connRecycled()
+	connGivenBack()
+	leaseReleased()
+	handleNotUnlocked()
+	handleUnlocked()
+	_ = decodeLeakingOnError()
+	_ = decodeClosingOnError()
+}
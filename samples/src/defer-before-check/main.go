@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+func deferBeforeCheck() error {
+	f, err := os.Create("output.txt")
+	defer f.Close() // want `f \(\*os.File\) is closed by a defer before err is checked`
+	if err != nil {
+		return err
+	}
+
+	println(f.Name())
+
+	return nil
+}
+
+func deferClosureBeforeCheck() error {
+	f, err := os.Open("main.go")
+	defer func() { // want `f \(\*os.File\) is closed by a defer before err is checked`
+		_ = f.Close()
+	}()
+
+	if err != nil {
+		return err
+	}
+
+	println(f.Name())
+
+	return nil
+}
+
+func deferWithoutCheck() {
+	f, err := os.Open("main.go")
+	defer f.Close() // want `f \(\*os.File\) is closed by a defer before err is checked`
+
+	println(err)
+}
+
+func deferAfterCheck() error {
+	res, err := http.Get("https://www.google.com")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	println(res.StatusCode)
+
+	return nil
+}
+
+func main() {
+	_ = deferBeforeCheck()
+	_ = deferClosureBeforeCheck()
+	deferWithoutCheck()
+	_ = deferAfterCheck()
+}
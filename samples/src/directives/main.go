@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+)
+
+func ignoredOnTheLine() {
+	f, _ := os.Open("file.txt") //closecheck:ignore the file is closed when the process exits
+
+	_ = f
+}
+
+func ignoredOnThePreviousLine() {
+	//nolint:errcheck,closecheck // the file is closed when the process exits
+	f, _ := os.Open("file.txt")
+
+	_ = f
+}
+
+// ignoredFunction opens files that are never closed
+//
+//closecheck:ignore the files are closed when the process exits
+func ignoredFunction() {
+	f, _ := os.Open("file.txt")
+	g, _ := os.Open("other.txt")
+
+	_, _ = f, g
+}
+
+func ignoredWithoutReason() {
+	// want +1 `//closecheck:ignore directive must explain why the diagnostics are ignored`
+	//closecheck:ignore
+	f, _ := os.Open("file.txt")
+
+	_ = f
+}
+
+func nolintWithoutReason() {
+	// want +1 `//nolint:closecheck directive must explain why the diagnostics are ignored`
+	//nolint:closecheck
+	f, _ := os.Open("file.txt")
+
+	_ = f
+}
+
+func staleDirective() {
+	f, _ := os.Open("file.txt")
+	// want +1 `//closecheck:ignore directive doesn't suppress any diagnostic`
+	//closecheck:ignore the file used to be leaked
+	defer f.Close()
+}
+
+func notIgnored() {
+	f, _ := os.Open("file.txt") // want `f \(\*os.File\) was not closed`
+
+	_ = f
+	//nolint:errcheck // not for closecheck
+}
+
+// keepIgnored keeps the file open until the process exits
+//
+//closecheck:ignore the annotation is kept until the callers are migrated
+//closecheck:takes-ownership g
+func keepIgnored(f *os.File) { // want keepIgnored:"f not closed"
+	_ = f
+}
+
+func main() {
+	ignoredOnTheLine()
+	ignoredOnThePreviousLine()
+	ignoredFunction()
+	ignoredWithoutReason()
+	nolintWithoutReason()
+	staleDirective()
+	notIgnored()
+	keepIgnored(nil)
+}
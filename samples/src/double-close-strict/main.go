@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+)
+
+func checkedClose() error {
+	f, err := os.Create("out.txt")
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	if _, err := f.WriteString("data"); err != nil {
+		return err
+	}
+
+	return f.Close() // want `f \(\*os.File\) is closed twice, it's also closed by the defer at line 13`
+}
+
+func main() {
+	_ = checkedClose()
+}
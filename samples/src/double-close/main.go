@@ -0,0 +1,118 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+func closeTwice() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	_ = f.Close()
+	_ = f.Close() // want `f \(\*os.File\) is closed twice, it's already closed at line 14`
+}
+
+func closeAndDefer() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	println(f.Name())
+
+	_ = f.Close() // want `f \(\*os.File\) is closed twice, it's also closed by the defer at line 24`
+}
+
+func deferTwice() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+	defer f.Close() // want `f \(\*os.File\) is closed twice, it's also closed by the defer at line 37`
+}
+
+func closeWith(c io.Closer) { // want closeWith:"c always closed"
+	_ = c.Close()
+}
+
+func closeInHelper() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	closeWith(f)
+
+	_ = f.Close() // want `f \(\*os.File\) is closed twice, it's already closed at line 51`
+}
+
+func closeOnDifferentPaths(flag bool) {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	if flag {
+		_ = f.Close()
+
+		return
+	}
+
+	defer f.Close()
+}
+
+func checkedClose() error {
+	f, err := os.Create("out.txt")
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	if _, err := f.WriteString("data"); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func uncheckedClose() {
+	f, err := os.Create("out.txt")
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	_, _ = f.WriteString("data")
+	_ = f.Close() // want `f \(\*os.File\) is closed twice, it's also closed by the defer at line 92`
+}
+
+func checkedCloseOfReadOnlyFile() error {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return f.Close() // want `f \(\*os.File\) is closed twice, it's also closed by the defer at line 104`
+}
+
+func main() {
+	closeTwice()
+	closeAndDefer()
+	deferTwice()
+	closeInHelper()
+	closeOnDifferentPaths(true)
+	_ = checkedClose()
+	uncheckedClose()
+	_ = checkedCloseOfReadOnlyFile()
+}
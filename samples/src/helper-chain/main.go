@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+func closeFirst(c io.Closer) { // want closeFirst:"c always closed"
+	closeSecond(c)
+}
+
+func closeSecond(c io.Closer) { // want closeSecond:"c always closed"
+	closeThird(c)
+}
+
+func closeThird(c io.Closer) { // want closeThird:"c always closed"
+	closeLast(c)
+}
+
+func closeLast(c io.Closer) { // want closeLast:"c always closed"
+	_ = c.Close()
+}
+
+func closeEven(c io.Closer, n int) { // want closeEven:"c always closed"
+	if n == 0 {
+		_ = c.Close()
+		return
+	}
+
+	closeOdd(c, n-1)
+}
+
+func closeOdd(c io.Closer, n int) { // want closeOdd:"c always closed"
+	if n == 0 {
+		_ = c.Close()
+		return
+	}
+
+	closeEven(c, n-1)
+}
+
+func neverClosed(c io.Closer, n int) { // want neverClosed:"c not closed"
+	if n > 0 {
+		neverClosed(c, n-1)
+	}
+}
+
+func closedThroughChain() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	closeFirst(f)
+}
+
+func closedThroughRecursion() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	closeEven(f, 3)
+}
+
+func notClosedThroughRecursion() {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed`
+	if err != nil {
+		return
+	}
+
+	neverClosed(f, 3)
+}
+
+func main() {
+	closedThroughChain()
+	closedThroughRecursion()
+	notClosedThroughRecursion()
+}
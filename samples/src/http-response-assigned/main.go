@@ -6,7 +6,7 @@ import (
 	"net/http"
 )
 
-func doReq() *http.Response {
+func doReq() *http.Response { // want doReq:"result 0 owned"
 	res, err := http.Get("https://www.google.com")
 	if err != nil {
 		panic(err)
@@ -18,17 +18,17 @@ func doReq() *http.Response {
 type closer struct {
 }
 
-func (c closer) closeBody(bodyToBeClosed io.Closer) { // want closeBody:"is not closer"
+func (c closer) closeBody(bodyToBeClosed io.Closer) { // want closeBody:"bodyToBeClosed always closed"
 	c.closeBodyWithContext(context.Background(), bodyToBeClosed)
 }
 
-func (c closer) closeBody2(bodyToBeClosed io.Closer) { // want closeBody2:"is closer"
+func (c closer) closeBody2(bodyToBeClosed io.Closer) { // want closeBody2:"bodyToBeClosed always closed"
 	if bodyToBeClosed != nil {
 		bodyToBeClosed.Close()
 	}
 }
 
-func (c closer) closeBodyWithContext(ctx context.Context, bodyToBeClosed io.Closer) { // want closeBodyWithContext:"is closer"
+func (c closer) closeBodyWithContext(ctx context.Context, bodyToBeClosed io.Closer) { // want closeBodyWithContext:"bodyToBeClosed always closed"
 	if err := bodyToBeClosed.Close(); err != nil {
 		panic("this shouldn't happen")
 	}
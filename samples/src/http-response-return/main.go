@@ -5,7 +5,7 @@ import (
 	"net/http"
 )
 
-func doReq() io.ReadCloser {
+func doReq() io.ReadCloser { // want doReq:"result 0 owned"
 	res, err := http.Get("https://www.google.com")
 	if err != nil {
 		panic(err)
@@ -14,7 +14,7 @@ func doReq() io.ReadCloser {
 	return res.Body
 }
 
-func doReq2() *http.Response {
+func doReq2() *http.Response { // want doReq2:"result 0 owned"
 	res, _ := http.Get("https://www.google.com")
 
 	return aCloser.doNothing(res)
@@ -23,11 +23,11 @@ func doReq2() *http.Response {
 type closer struct {
 }
 
-func (c closer) closeBody(bodyToBeClosed io.Closer) {
+func (c closer) closeBody(bodyToBeClosed io.Closer) { // want closeBody:"bodyToBeClosed always closed"
 	_ = bodyToBeClosed.Close()
 }
 
-func (c closer) doNothing(res *http.Response) *http.Response {
+func (c closer) doNothing(res *http.Response) *http.Response { // want doNothing:"res returned" doNothing:"result 0 owned"
 	return res
 }
 
@@ -40,7 +40,7 @@ var (
 	aWrapper = wrapper{aCloser}
 )
 
-func callCloser(res *http.Response) bool {
+func callCloser(res *http.Response) bool { // want callCloser:"res always closed"
 	defer aWrapper.closer.closeBody(res.Body)
 
 	return true
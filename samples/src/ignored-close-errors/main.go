@@ -0,0 +1,92 @@
+package main
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+func writeFile(data []byte) (err error) {
+	f, err := os.Create("out.txt")
+	if err != nil {
+		return err
+	}
+
+	defer f.Close() // want `the error returned by f.Close\(\) is ignored, the data written to f \(\*os.File\) can be lost`
+
+	_, err = f.Write(data)
+
+	return err
+}
+
+func appendFile(data []byte) error {
+	f, err := os.OpenFile("out.txt", os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close() // want `the error returned by f.Close\(\) is ignored, the data written to f \(\*os.File\) can be lost`
+
+		return err
+	}
+
+	return f.Close()
+}
+
+func compress(out io.Writer, data []byte) (n int, err error) {
+	w := gzip.NewWriter(out)
+	defer w.Close() // want `the error returned by w.Close\(\) is ignored, the data written to w \(\*compress/gzip.Writer\) can be lost`
+
+	return w.Write(data)
+}
+
+func archive(out io.Writer) error {
+	w := zip.NewWriter(out)
+
+	if _, err := w.Create("empty.txt"); err != nil {
+		_ = w.Close() // want `the error returned by w.Close\(\) is ignored, the data written to w \(\*archive/zip.Writer\) can be lost`
+
+		return err
+	}
+
+	return w.Close()
+}
+
+func writeLines(lines []string) error {
+	f, err := os.Create("out.txt")
+	if err != nil {
+		return err
+	}
+
+	defer f.Close() // want `the error returned by f.Close\(\) is ignored, the data written to f \(\*os.File\) can be lost, no fix is suggested because the function has no named error result to return it`
+
+	for _, line := range lines {
+		if _, err := f.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readFile() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	println(f.Name())
+}
+
+func main() {
+	_ = writeFile(nil)
+	_ = appendFile(nil)
+	_, _ = compress(os.Stdout, nil)
+	_ = archive(os.Stdout)
+	_ = writeLines(nil)
+	readFile()
+}
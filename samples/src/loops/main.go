@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"time"
+)
+
+func deferInLoop(names []string) {
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			panic(err)
+		}
+
+		defer f.Close() // want `f \(\*os.File\) is closed by a defer inside a loop, it won't be closed until the function returns`
+	}
+}
+
+func deferInClosureInLoop(names []string) {
+	for _, name := range names {
+		func() {
+			f, err := os.Open(name)
+			if err != nil {
+				panic(err)
+			}
+
+			defer f.Close()
+		}()
+	}
+}
+
+func deferInLoopThatAlwaysReturns(urls []string) int {
+	for _, url := range urls {
+		res, err := http.Get(url)
+		if err != nil {
+			continue
+		}
+
+		defer res.Body.Close()
+
+		return res.StatusCode
+	}
+
+	return 0
+}
+
+func closedOnEveryIteration(names []string) {
+	for i := 0; i < len(names); i++ {
+		f, err := os.Open(names[i])
+		if err != nil {
+			panic(err)
+		}
+
+		println(f.Name())
+
+		_ = f.Close()
+	}
+}
+
+func overwrittenOnNextIteration(names []string) error {
+	var f *os.File
+
+	for _, name := range names {
+		var err error
+
+		f, err = os.Open(name) // want `f \(\*os.File\) is overwritten on the next iteration of the loop without being closed`
+		if err != nil {
+			return err
+		}
+
+		time.Sleep(time.Second)
+	}
+
+	return f.Close()
+}
+
+func main() {
+	names := []string{"main.go"}
+
+	deferInLoop(names)
+	deferInClosureInLoop(names)
+	_ = deferInLoopThatAlwaysReturns(names)
+	closedOnEveryIteration(names)
+	_ = overwrittenOnNextIteration(names)
+}
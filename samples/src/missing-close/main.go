@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+)
+
+func doReq() *http.Response { // want doReq:"result 0 owned"
+	res, err := http.Get("https://www.google.com")
+	if err != nil {
+		panic(err)
+	}
+
+	return res
+}
+
+func closeAfterErrorCheck() error {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 26`
+	if err != nil {
+		return err
+	}
+
+	println(f.Name())
+
+	return nil
+}
+
+func closeAfterAssignment() {
+	res := doReq() // want `res.Body \(io.ReadCloser\) was not closed on return at line 33`
+
+	println(res.StatusCode)
+}
+
+func closeEveryPath(flag bool) error {
+	res, err := http.Get("https://www.google.com") // want `res.Body \(io.ReadCloser\) was not closed on return at line 44` `res.Body \(io.ReadCloser\) was not closed on return at line 47`
+	if err != nil {
+		return err
+	}
+
+	println(res.StatusCode)
+
+	if flag {
+		return errors.New("flag is set")
+	}
+
+	return nil
+}
+
+func closedInSomePaths(flag bool) error {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 57`
+	if err != nil {
+		return err
+	}
+
+	if flag {
+		return errors.New("flag is set")
+	}
+
+	return f.Close()
+}
+
+func main() {
+	_ = closeAfterErrorCheck()
+	closeAfterAssignment()
+	_ = closeEveryPath(true)
+	_ = closedInSomePaths(true)
+}
@@ -8,7 +8,7 @@ func (c *customCloser) Close() error {
 	return nil
 }
 
-func closer() *customCloser {
+func closer() *customCloser { // want closer:"result 0 owned"
 	return &customCloser{}
 }
 
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net"
+	"os"
+)
+
+type Server struct {
+	listener net.Listener // want `field Server.listener \(net.Listener\) holds a closer but Server doesn't have a method that releases it`
+	addr     string
+}
+
+func (s *Server) Listen() error {
+	var err error
+
+	s.listener, err = net.Listen("tcp", s.addr)
+
+	return err
+}
+
+type Client struct { // want Client:"Close releases conn"
+	conn net.Conn
+	log  *os.File // want `field Client.log \(\*os.File\) holds a closer but Client.Close doesn't release it`
+}
+
+func Dial(addr string) (*Client, error) { // want Dial:"result 0 owned"
+	c := &Client{}
+
+	var err error
+
+	c.conn, err = net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log, err = os.Create("client.log")
+	if err != nil {
+		_ = c.conn.Close()
+
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+type Pool struct { // want Pool:"Close releases idle"
+	idle *os.File
+}
+
+func NewPool() *Pool { // want NewPool:"result 0 owned"
+	return &Pool{idle: mustOpen()}
+}
+
+func (p *Pool) Close() error {
+	return p.idle.Close()
+}
+
+type Cache struct {
+	file *os.File // want `field Cache.file \(\*os.File\) holds a closer but Cache doesn't have a method that releases it`
+}
+
+func NewCache() *Cache {
+	return &Cache{file: mustOpen()}
+}
+
+type Borrower struct {
+	conn net.Conn
+}
+
+func NewBorrower(conn net.Conn) *Borrower { // want NewBorrower:"conn returned"
+	return &Borrower{conn: conn}
+}
+
+func mustOpen() *os.File { // want mustOpen:"result 0 owned"
+	f, err := os.Open("main.go")
+	if err != nil {
+		panic(err)
+	}
+
+	return f
+}
+
+func main() {
+	s := &Server{addr: ":8080"}
+	_ = s.Listen()
+
+	c, err := Dial(":8080")
+	if err != nil {
+		panic(err)
+	}
+
+	defer c.Close()
+
+	p := NewPool()
+	defer p.Close()
+
+	println(NewCache().file.Name())
+	println(NewBorrower(nil).conn)
+}
@@ -0,0 +1,114 @@
+package main
+
+import "os"
+
+type Conn struct { // want Conn:"Close releases f"
+	f *os.File
+}
+
+func (c *Conn) Close() error {
+	return c.f.Close()
+}
+
+// Open opens a connection that closes the file when it's closed
+//
+//closecheck:returns-owned
+func Open(name string) (*Conn, error) { // want Open:"no closers" Open:"result 0 owned"
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{}
+	c.f = f
+
+	return c, nil
+}
+
+func openWithoutAnnotation(name string) (*Conn, error) { // want openWithoutAnnotation:"result 0 owned"
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{}
+	c.f = f
+
+	return c, nil
+}
+
+func openComposite(name string) (*Conn, error) { // want openComposite:"result 0 owned"
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{f: f}
+
+	return c, nil
+}
+
+func openAndDrop(name string) error {
+	f, err := os.Open(name) // want `f \(\*os.File\) was not closed`
+	if err != nil {
+		return err
+	}
+
+	c := &Conn{}
+	c.f = f
+
+	return nil
+}
+
+type Pool struct {
+	files []*os.File
+}
+
+// Put keeps the file to close it when the pool is closed
+//
+//closecheck:takes-ownership f
+func (p *Pool) Put(f *os.File) { // want Put:"f owned"
+	p.files = append(p.files, f)
+}
+
+//closecheck:takes-ownership g // want `//closecheck:takes-ownership: g is not a parameter of keep`
+func keep(f *os.File) { // want keep:"f not closed"
+	_ = f
+}
+
+func usePool(p *Pool) {
+	f, err := os.Open("file.txt")
+	if err != nil {
+		return
+	}
+
+	p.Put(f)
+}
+
+func useConn() {
+	c, err := Open("file.txt") // want `c \(\*ownership.Conn\) was not closed`
+	if err != nil {
+		return
+	}
+
+	_ = c
+}
+
+func main() {
+	p := &Pool{}
+	usePool(p)
+	useConn()
+	keep(nil)
+
+	c, err := openWithoutAnnotation("file.txt")
+	if err == nil {
+		c.Close()
+	}
+
+	c, err = openComposite("file.txt")
+	if err == nil {
+		c.Close()
+	}
+
+	_ = openAndDrop("file.txt")
+}
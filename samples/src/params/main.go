@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+var kept []io.Closer
+
+func closeSecond(a, b io.Closer) { // want closeSecond:"a not closed, b always closed"
+	_ = b.Close()
+}
+
+func logAndClose(format string, c io.Closer, args ...interface{}) { // want logAndClose:"c always closed"
+	println(format, args)
+
+	_ = c.Close()
+}
+
+func keep(c io.Closer) { // want keep:"c stored"
+	kept = append(kept, c)
+}
+
+func identity(c io.Closer) io.Closer { // want identity:"c returned" identity:"result 0 owned"
+	return c
+}
+
+type conn struct { // want conn:"Close releases f"
+	f *os.File
+}
+
+func (c *conn) Close() error {
+	return c.f.Close()
+}
+
+func (c *conn) shutdown() { // want shutdown:"receiver always closed"
+	_ = c.Close()
+}
+
+func dial() (*conn, error) { // want dial:"result 0 owned"
+	f, err := os.Open("main.go")
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{f: f}, nil
+}
+
+func onlySecondIsClosed() {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 57` `f \(\*os.File\) was not closed on return at line 61`
+	if err != nil {
+		return
+	}
+
+	g, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	closeSecond(f, g)
+}
+
+func variadicIsNotClosed() {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 71` `f \(\*os.File\) was not closed on return at line 75`
+	if err != nil {
+		return
+	}
+
+	g, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	logAndClose("%v", g, f)
+}
+
+func keptForLater() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	keep(f)
+}
+
+func closedByMethod() {
+	c, err := dial()
+	if err != nil {
+		return
+	}
+
+	defer c.shutdown()
+}
+
+func closedByMethodExpression() {
+	c, err := dial()
+	if err != nil {
+		return
+	}
+
+	(*conn).shutdown(c)
+}
+
+func closeIfDone(c io.Closer, done bool) { // want closeIfDone:"c sometimes closed"
+	if done {
+		_ = c.Close()
+	}
+}
+
+func closedConditionally(done bool) {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 117`
+	if err != nil {
+		return
+	}
+
+	closeIfDone(f, done)
+}
+
+func main() {
+	onlySecondIsClosed()
+	variadicIsNotClosed()
+	keptForLater()
+	closedByMethod()
+	closedByMethodExpression()
+	closedConditionally(true)
+}
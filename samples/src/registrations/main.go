@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// Registry releases the closers added to it when the program exits
+type Registry interface {
+	Add(c io.Closer)
+}
+
+func openWithCleanup(tb testing.TB) {
+	f, err := os.Open("main.go")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	tb.Cleanup(func() {
+		_ = f.Close()
+	})
+
+	println(f.Name())
+}
+
+func openWithSeveralCleanups(t *testing.T) {
+	f, err := os.Open("main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = f.Sync() })
+	t.Cleanup(func() { _ = f.Close() })
+
+	println(f.Name())
+}
+
+func openWithCleanupThatDoesntClose(t *testing.T) {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed`
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { println(f.Name()) })
+}
+
+func openWithFinalizer() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		panic(err)
+	}
+
+	runtime.SetFinalizer(f, func(f *os.File) {
+		_ = f.Close()
+	})
+}
+
+func closeFile(f *os.File) { // want closeFile:"f always closed"
+	_ = f.Close()
+}
+
+func closeWhenCollected(f *os.File) { // want closeWhenCollected:"f always closed"
+	runtime.SetFinalizer(f, func(f *os.File) {
+		_ = f.Close()
+	})
+}
+
+func removeFinalizer(f *os.File) { // want removeFinalizer:"f not closed"
+	runtime.SetFinalizer(f, nil)
+}
+
+func openWithFinalizerFunc() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		panic(err)
+	}
+
+	runtime.SetFinalizer(f, closeFile)
+}
+
+func openWithFinalizerRemoved() {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed`
+	if err != nil {
+		panic(err)
+	}
+
+	runtime.SetFinalizer(f, nil)
+}
+
+func openWithFinalizerThatDoesntClose() {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed`
+	if err != nil {
+		panic(err)
+	}
+
+	runtime.SetFinalizer(f, func(f *os.File) {
+		println(f.Name())
+	})
+}
+
+func openWithRegistry(r Registry) {
+	f, err := os.Open("main.go")
+	if err != nil {
+		panic(err)
+	}
+
+	r.Add(f)
+}
+
+func main() {
+	openWithCleanup(&testing.T{})
+	openWithSeveralCleanups(&testing.T{})
+	openWithCleanupThatDoesntClose(&testing.T{})
+	openWithFinalizer()
+	openWithFinalizerFunc()
+	openWithFinalizerRemoved()
+	openWithFinalizerThatDoesntClose()
+	openWithRegistry(nil)
+}
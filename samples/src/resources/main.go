@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"os"
+	"os/exec"
+	"time"
+)
+
+func tickerNotStopped() {
+	ticker := time.NewTicker(time.Second) // want `ticker \(\*time.Ticker\) was not stopped on return at line 16`
+
+	<-ticker.C
+}
+
+func tickerStopped() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	<-ticker.C
+}
+
+func cancelNotCalled() context.Context {
+	ctx, cancel := context.WithCancel(context.Background()) // want `cancel \(context.CancelFunc\) was not called on return at line 30`
+
+	_ = cancel
+
+	return ctx
+}
+
+func cancelCalled() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	<-ctx.Done()
+}
+
+func stopTimeout(cancel context.CancelFunc) { // want stopTimeout:"cancel always closed"
+	cancel()
+}
+
+func cancelCalledByHelper() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer stopTimeout(cancel)
+
+	<-ctx.Done()
+}
+
+func txNotFinished(db *sql.DB) error { // want txNotFinished:"db not closed"
+	tx, err := db.Begin() // want `tx \(\*database/sql.Tx\) was not committed or rolled back on return at line 59`
+	if err != nil {
+		return err
+	}
+
+	_ = tx
+
+	return nil
+}
+
+func txCommitted(db *sql.DB) error { // want txCommitted:"db not closed"
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM users"); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func cmdNotWaited() error {
+	cmd := exec.Command("ls") // want `cmd \(\*os/exec.Cmd\) was not waited for on return at line 79`
+
+	return cmd.Start()
+}
+
+func cmdRun() error {
+	cmd := exec.Command("ls")
+
+	return cmd.Run()
+}
+
+func writerNotFlushed() {
+	w := bufio.NewWriter(os.Stdout) // want `w \(\*bufio.Writer\) was not flushed on return at line 92`
+
+	_, _ = w.WriteString("hello")
+}
+
+func writerFlushed() error {
+	w := bufio.NewWriter(os.Stdout)
+
+	_, _ = w.WriteString("hello")
+
+	return w.Flush()
+}
+
+func main() {
+	tickerNotStopped()
+	tickerStopped()
+	_ = cancelNotCalled()
+	cancelCalled()
+	cancelCalledByHelper()
+	_ = txNotFinished(nil)
+	_ = txCommitted(nil)
+	_ = cmdNotWaited()
+	_ = cmdRun()
+	writerNotFlushed()
+	_ = writerFlushed()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+)
+
+type holder struct {
+	body io.Closer
+}
+
+func closeAlias() {
+	res, err := http.Get("https://www.google.com")
+	if err != nil {
+		panic(err)
+	}
+
+	b := res.Body
+	b.Close()
+}
+
+func closePhi(flag bool) {
+	res, err := http.Get("https://www.google.com")
+	if err != nil {
+		panic(err)
+	}
+
+	var b io.Closer = http.NoBody
+	if flag {
+		b = res.Body
+	} else {
+		b = res.Body
+	}
+
+	b.Close()
+}
+
+func closeThroughLocalHelper() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		panic(err)
+	}
+
+	closeIt(passThrough(f))
+}
+
+func passThrough(c io.Closer) io.Closer { // want passThrough:"c returned" passThrough:"result 0 owned"
+	return c
+}
+
+func closeIt(c io.Closer) { // want closeIt:"c always closed"
+	_ = c.Close()
+}
+
+func storeInField(h *holder) {
+	f, err := os.Open("main.go")
+	if err != nil {
+		panic(err)
+	}
+
+	h.body = f
+}
+
+func closeInClosure() {
+	res, err := http.Get("https://www.google.com")
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		_ = res.Body.Close()
+	}()
+}
+
+func aliasNotClosed() {
+	res, err := http.Get("https://www.google.com") // want `res.Body \(io.ReadCloser\) was not closed`
+	if err != nil {
+		panic(err)
+	}
+
+	b := res.Body
+	_ = b
+}
+
+// register closes the file when the process exits
+//
+//closecheck:takes-ownership f
+func register(f *os.File) { // want register:"f owned"
+}
+
+func closeWhenRegistered() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		panic(err)
+	}
+
+	register(f)
+}
+
+func closeAt(p **os.File) {
+	_ = (*p).Close()
+}
+
+func nameAt(p **os.File) string {
+	return (*p).Name()
+}
+
+func closeThroughAddress() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		panic(err)
+	}
+
+	closeAt(&f)
+}
+
+func readThroughAddress() {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed`
+	if err != nil {
+		panic(err)
+	}
+
+	println(nameAt(&f))
+}
+
+func main() {
+	closeWhenRegistered()
+	closeAlias()
+	closePhi(true)
+	closeThroughLocalHelper()
+	storeInField(&holder{})
+	closeInClosure()
+	aliasNotClosed()
+	closeThroughAddress()
+	readThroughAddress()
+}
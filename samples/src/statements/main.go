@@ -0,0 +1,142 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+func closeInSwitch(c io.Closer, n int) { // want closeInSwitch:"c sometimes closed"
+	switch n {
+	case 1:
+		_ = c.Close()
+	}
+}
+
+func closeInTypeSwitch(c io.Closer, v interface{}) { // want closeInTypeSwitch:"c sometimes closed"
+	switch v.(type) {
+	case string:
+		_ = c.Close()
+	}
+}
+
+func closeInSelect(c io.Closer, ch chan int) { // want closeInSelect:"c always closed"
+	select {
+	case <-ch:
+		_ = c.Close()
+	}
+}
+
+func closeInFor(c io.Closer) { // want closeInFor:"c sometimes closed"
+	for i := 0; i < 1; i++ {
+		_ = c.Close()
+	}
+}
+
+func closeInRange(c io.Closer, items []int) { // want closeInRange:"c sometimes closed"
+	for range items {
+		_ = c.Close()
+	}
+}
+
+func closeInBlock(c io.Closer) { // want closeInBlock:"c always closed"
+	{
+		_ = c.Close()
+	}
+}
+
+func closeInLabeledStmt(c io.Closer) { // want closeInLabeledStmt:"c always closed"
+loop:
+	for {
+		_ = c.Close()
+		break loop
+	}
+}
+
+func closeInElse(c io.Closer, flag bool) { // want closeInElse:"c sometimes closed"
+	if flag {
+		println("not closed")
+	} else {
+		_ = c.Close()
+	}
+}
+
+func openInSwitch(n int) {
+	switch n {
+	case 1:
+		f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 73`
+		if err != nil {
+			panic(err)
+		}
+
+		println(f.Name())
+	}
+}
+
+func openInSelect(ch chan int) {
+	select {
+	case <-ch:
+		f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 85`
+		if err != nil {
+			panic(err)
+		}
+
+		println(f.Name())
+	}
+}
+
+func openInRange(names []string) {
+	for _, name := range names {
+		f, err := os.Open(name) // want `f \(\*os.File\) is overwritten on the next iteration of the loop without being closed` `f \(\*os.File\) was not closed on return at line 96`
+		if err != nil {
+			panic(err)
+		}
+
+		println(f.Name())
+	}
+}
+
+func openInBlock() {
+	{
+		f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 107`
+		if err != nil {
+			panic(err)
+		}
+
+		println(f.Name())
+	}
+}
+
+// most of the helpers close the file only sometimes, so it's still open on some paths
+func closedByHelpers(n int, v interface{}, ch chan int, flag bool) {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed on return at line 134`
+	if err != nil {
+		panic(err)
+	}
+
+	switch n {
+	case 0:
+		closeInSwitch(f, n)
+	case 1:
+		closeInTypeSwitch(f, v)
+	case 2:
+		closeInSelect(f, ch)
+	case 3:
+		closeInFor(f)
+	case 4:
+		closeInRange(f, []int{n})
+	case 5:
+		closeInBlock(f)
+	case 6:
+		closeInLabeledStmt(f)
+	default:
+		closeInElse(f, flag)
+	}
+}
+
+func main() {
+	openInSwitch(1)
+	openInSelect(make(chan int))
+	openInRange([]string{"main.go"})
+	openInBlock()
+	closedByHelpers(1, nil, nil, true)
+}
@@ -2,11 +2,11 @@ package main
 
 import "net/http"
 
-type Closable struct {
+type Closable struct { // want Closable:"Close releases resp"
 	resp *http.Response
 }
 
-func New() (*Closable, error) {
+func New() (*Closable, error) { // want New:"result 0 owned"
 	resp, err := http.Get("https://www.google.com")
 	if err != nil {
 		return nil, err
@@ -21,6 +21,27 @@ func (c *Closable) Close() error {
 	return c.resp.Body.Close()
 }
 
+type Leaky struct { // want Leaky:"Close releases no fields"
+	resp *http.Response
+}
+
+func NewLeaky() (*Leaky, error) { // want NewLeaky:"result 0 owned"
+	resp, err := http.Get("https://www.google.com")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Leaky{
+		resp: resp, // want `resp.Body \(io.ReadCloser\) is stored in Leaky.resp but Leaky.Close doesn't release it`
+	}, nil
+}
+
+func (l *Leaky) Close() error {
+	l.resp = nil
+
+	return nil
+}
+
 func main() {
 	c, err := New()
 	if err != nil {
@@ -28,4 +49,11 @@ func main() {
 	}
 
 	c.Close()
+
+	l, err := NewLeaky()
+	if err != nil {
+		panic(err)
+	}
+
+	l.Close()
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+func readAfterClose() {
+	res, err := http.Get("https://www.google.com")
+	if err != nil {
+		return
+	}
+
+	_ = res.Body.Close()
+
+	println(res.StatusCode)
+
+	_, _ = io.ReadAll(res.Body) // want `res.Body \(io.ReadCloser\) is used after being closed`
+}
+
+func writeAfterClose() error {
+	f, err := os.Create("out.txt")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString("header"); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	_, err = f.WriteString("footer") // want `f \(\*os.File\) is used after being closed`
+
+	return err
+}
+
+func closedOnEveryBranch(flag bool) string {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return ""
+	}
+
+	if flag {
+		_ = f.Close()
+	} else {
+		f.Close()
+	}
+
+	info, _ := f.Stat() // want `f \(\*os.File\) is used after being closed`
+
+	return info.Name()
+}
+
+func closedOnSomeBranches(flag bool) {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	if flag {
+		_ = f.Close()
+		f = nil
+	}
+
+	if f != nil {
+		println(f.Name())
+		_ = f.Close()
+	}
+}
+
+func closedInLoop(names []string) {
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+
+		_ = f.Close()
+
+		_, _ = f.Seek(0, io.SeekStart) // want `f \(\*os.File\) is used after being closed`
+	}
+}
+
+func nameAfterClose() string {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return ""
+	}
+
+	_ = f.Close()
+
+	return f.Name()
+}
+
+func bodyReplacedAfterClose() {
+	res, err := http.Get("https://www.google.com")
+	if err != nil {
+		return
+	}
+
+	_ = res.Body.Close()
+
+	res.Body = io.NopCloser(strings.NewReader("closecheck"))
+
+	_, _ = io.ReadAll(res.Body)
+}
+
+func deferredClose() {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	println(f.Name())
+}
+
+func main() {
+	readAfterClose()
+	_ = writeAfterClose()
+	_ = closedOnEveryBranch(true)
+	closedOnSomeBranches(true)
+	closedInLoop([]string{"main.go"})
+	deferredClose()
+	_ = nameAfterClose()
+	bodyReplacedAfterClose()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+func closeAll(cs ...io.Closer) { // want closeAll:"cs always closed"
+	for _, c := range cs {
+		_ = c.Close()
+	}
+}
+
+func closeAllJoined(cs []io.Closer) error { // want closeAllJoined:"cs always closed"
+	errs := []error{}
+
+	for _, c := range cs {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func closeFirst(cs ...io.Closer) { // want closeFirst:"cs not closed"
+	for _, c := range cs {
+		println(c)
+	}
+}
+
+func forwardAll(cs ...io.Closer) { // want forwardAll:"cs always closed"
+	closeAll(cs...)
+}
+
+func openAll() {
+	a, err := os.Open("a")
+	if err != nil {
+		return
+	}
+
+	b, err := os.Open("b")
+	if err != nil {
+		closeAll(a)
+		return
+	}
+
+	c, err := os.Open("c")
+	if err != nil {
+		closeAll(a, b)
+		return
+	}
+
+	defer closeAll(a, b, c)
+}
+
+func openAndJoin() error {
+	a, err := os.Open("a")
+	if err != nil {
+		return err
+	}
+
+	b, err := os.Open("b")
+	if err != nil {
+		return closeAllJoined([]io.Closer{a})
+	}
+
+	return closeAllJoined([]io.Closer{a, b})
+}
+
+func openAndForward() {
+	a, err := os.Open("a")
+	if err != nil {
+		return
+	}
+
+	forwardAll(a)
+}
+
+func openAndLeak() {
+	a, err := os.Open("a") // want `a \(\*os.File\) was not closed`
+	if err != nil {
+		return
+	}
+
+	closeFirst(a)
+}
+
+func main() {
+	openAll()
+	_ = openAndJoin()
+	openAndForward()
+	openAndLeak()
+}
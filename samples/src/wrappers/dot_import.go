@@ -0,0 +1,11 @@
+package main
+
+import (
+	. "io"
+	"strings"
+)
+
+func nopCloserWithDotImport() {
+	rc := NopCloser(strings.NewReader("closecheck"))
+	println(rc)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	stdio "io"
+	"net/http/httptest"
+	"os"
+	"strings"
+)
+
+func nopCloserWithAlias() {
+	rc := stdio.NopCloser(strings.NewReader("closecheck"))
+	println(rc)
+}
+
+func nopCloserAssigned() {
+	rc := io.NopCloser(strings.NewReader("closecheck"))
+	println(rc)
+}
+
+func recordedResponse() {
+	rec := httptest.NewRecorder()
+	res := rec.Result()
+	println(res.StatusCode)
+}
+
+// closing the gzip reader doesn't close the file
+func readGzip() error {
+	f, err := os.Open("main.go.gz") // want `f \(\*os.File\) was not closed on return at line 38` `f \(\*os.File\) was not closed on return at line 45`
+	if err != nil {
+		return err
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+
+	defer gz.Close()
+
+	_, err = io.ReadAll(gz)
+
+	return err
+}
+
+func readGzipClosingOnError() error {
+	f, err := os.Open("main.go.gz") // want `f \(\*os.File\) was not closed on return at line 64`
+	if err != nil {
+		return err
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	defer gz.Close()
+
+	_, err = io.ReadAll(gz)
+
+	return err
+}
+
+func readGzipClosingBoth() error {
+	f, err := os.Open("main.go.gz")
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+
+	defer gz.Close()
+
+	_, err = io.ReadAll(gz)
+
+	return err
+}
+
+func openBuffered() (*bufio.Reader, error) {
+	f, err := os.Open("main.go")
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(f)
+
+	return r, nil
+}
+
+func buffered(f *os.File) *bufio.Reader { // want buffered:"f returned"
+	return bufio.NewReader(f)
+}
+
+func openBufferedAndForget() {
+	f, err := os.Open("main.go") // want `f \(\*os.File\) was not closed`
+	if err != nil {
+		return
+	}
+
+	r := bufio.NewReader(f)
+	println(r.Size())
+}
+
+func main() {
+	nopCloserWithAlias()
+	nopCloserWithDotImport()
+	nopCloserAssigned()
+	recordedResponse()
+	_ = readGzip()
+	_ = readGzipClosingOnError()
+	_ = readGzipClosingBoth()
+	_, _ = openBuffered()
+	openBufferedAndForget()
+	_ = buffered(nil)
+}